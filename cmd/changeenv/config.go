@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"envchanger/internal/envpath"
+)
+
+func newConfigCommand(opts *envpath.Options, output *outputFormat) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "config",
+		Short:         "Inspect the effective configuration.",
+		Args:          validateNoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:           "show",
+		Short:         "Print the merged configuration and where every entry came from.",
+		Args:          validateNoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(cmd, *output, outputText, outputJSON); err != nil {
+				return err
+			}
+			cfg, err := loadConfig(opts)
+			if err != nil {
+				return err
+			}
+			if *output == outputJSON {
+				return writeJSON(cmd.OutOrStdout(), newConfigJSON(cfg))
+			}
+			homeDir, _ := os.UserHomeDir()
+			printConfig(cmd.OutOrStdout(), cfg, homeDir)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:           "check",
+		Short:         "Report problems in the configuration files.",
+		Long:          `Report unreadable or malformed files, duplicate environment names, names that shadow each other case-insensitively, and names containing path separators. Exits with status 9 when problems are found.`,
+		Args:          validateNoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(cmd, *output, outputText, outputJSON); err != nil {
+				return err
+			}
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("determine current directory: %w", err)
+			}
+			cfg, err := envpath.LoadConfigForCheck(cwd, opts.ConfigPath)
+			if err != nil {
+				return err
+			}
+			issues := cfg.Check()
+			if *output == outputJSON {
+				out := make([]issueJSON, len(issues))
+				for i, issue := range issues {
+					out[i] = issueJSON{Origin: issue.Origin.String(), Message: issue.Message}
+				}
+				if err := writeJSON(cmd.OutOrStdout(), out); err != nil {
+					return err
+				}
+				if len(issues) > 0 {
+					return &exitStatusError{code: exitDifferences}
+				}
+				return nil
+			}
+			homeDir, _ := os.UserHomeDir()
+			if printIssues(cmd.OutOrStdout(), issues, homeDir) {
+				return &exitStatusError{code: exitDifferences}
+			}
+			return nil
+		},
+	})
+
+	return cmd
+}
+
+func loadConfig(opts *envpath.Options) (*envpath.Config, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("determine current directory: %w", err)
+	}
+	return envpath.LoadConfig(cwd, opts.ConfigPath)
+}
+
+func printConfig(w io.Writer, cfg *envpath.Config, homeDir string) {
+	fmt.Fprintln(w, "Files:")
+	if len(cfg.Files) == 0 {
+		fmt.Fprintln(w, "  (none)")
+	}
+	for _, file := range cfg.Files {
+		fmt.Fprintf(w, "  %s\n", displayPath(file, homeDir))
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Environments:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, env := range cfg.Envs {
+		fmt.Fprintf(tw, "  %s\t%s\n", env.Name, formatOrigin(env.Origin, homeDir))
+		if len(env.Dirs.Value) > 0 {
+			fmt.Fprintf(tw, "    dirs %s\t%s\n", quoteList(env.Dirs.Value), formatOrigin(env.Dirs.Origin, homeDir))
+		}
+		for _, alias := range env.Aliases {
+			fmt.Fprintf(tw, "    alias %s\t%s\n", alias.Name, formatOrigin(alias.Origin, homeDir))
+		}
+		if env.Description.Value != "" {
+			fmt.Fprintf(tw, "    description %s\t%s\n", strconv.Quote(env.Description.Value), formatOrigin(env.Description.Origin, homeDir))
+		}
+		for _, key := range sortedKeys(env.Meta) {
+			meta := env.Meta[key]
+			fmt.Fprintf(tw, "    meta %s=%s\t%s\n", key, strconv.Quote(meta.Value), formatOrigin(meta.Origin, homeDir))
+		}
+	}
+	tw.Flush()
+
+	if len(cfg.Patterns) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Patterns:")
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, pattern := range cfg.Patterns {
+			fmt.Fprintf(tw, "  %s\t%s\n", pattern.Raw, formatOrigin(pattern.Origin, homeDir))
+		}
+		tw.Flush()
+	}
+
+	if len(cfg.Ignore) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Ignore rules:")
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, rule := range cfg.Ignore {
+			fmt.Fprintf(tw, "  %s\t%s\n", rule.Raw, formatOrigin(rule.Origin, homeDir))
+		}
+		tw.Flush()
+	}
+
+	if len(cfg.Layouts) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Layouts:")
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, layout := range cfg.Layouts {
+			fmt.Fprintf(tw, "  %s\t%s\n", layout.Template, formatOrigin(layout.Origin, homeDir))
+		}
+		tw.Flush()
+	}
+
+	if len(cfg.Rules) > 0 {
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "Rewrite rules:")
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, rule := range cfg.Rules {
+			fmt.Fprintf(tw, "  %s -> %s: %s => %s\t%s\n", envOrAny(rule.From), envOrAny(rule.To), strconv.Quote(rule.Match), strconv.Quote(rule.Replace), formatOrigin(rule.Origin, homeDir))
+		}
+		tw.Flush()
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Settings:")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "  root_markers = %s\t%s\n", quoteList(rootMarkersValue(cfg)), formatOrigin(cfg.RootMarkers.Origin, homeDir))
+	fmt.Fprintf(tw, "  env_marker = %q\t%s\n", envMarkerValue(cfg), formatOrigin(cfg.EnvMarker.Origin, homeDir))
+	fmt.Fprintf(tw, "  case = %q\t%s\n", caseValue(cfg), formatOrigin(cfg.Case.Origin, homeDir))
+	fmt.Fprintf(tw, "  occurrence = %q\t%s\n", occurrenceValue(cfg), formatOrigin(cfg.Occurrence.Origin, homeDir))
+	fmt.Fprintf(tw, "  token_boundaries = %q\t%s\n", tokenBoundariesValue(cfg), formatOrigin(cfg.TokenBoundaries.Origin, homeDir))
+	tw.Flush()
+}
+
+func envOrAny(env string) string {
+	if env == "" {
+		return "*"
+	}
+	return env
+}
+
+func rootMarkersValue(cfg *envpath.Config) []string {
+	if len(cfg.RootMarkers.Value) == 0 {
+		return []string{".git"}
+	}
+	return cfg.RootMarkers.Value
+}
+
+func envMarkerValue(cfg *envpath.Config) string {
+	if cfg.EnvMarker.Value == "" {
+		return ".cenv-env"
+	}
+	return cfg.EnvMarker.Value
+}
+
+func tokenBoundariesValue(cfg *envpath.Config) string {
+	if cfg.TokenBoundaries.Value == "" {
+		return "-_."
+	}
+	return cfg.TokenBoundaries.Value
+}
+
+func caseValue(cfg *envpath.Config) envpath.CaseMode {
+	if cfg.Case.Value == "" {
+		return envpath.CaseInsensitive
+	}
+	return cfg.Case.Value
+}
+
+func occurrenceValue(cfg *envpath.Config) envpath.Occurrence {
+	if cfg.Occurrence.Value == "" {
+		return envpath.OccurrenceInnermostUnderRoot
+	}
+	return cfg.Occurrence.Value
+}
+
+type issueJSON struct {
+	Origin  string `json:"origin"`
+	Message string `json:"message"`
+}
+
+// configJSON is the JSON form of the merged configuration. Origins are
+// written like "file:line", "$VARIABLE" or "built-in".
+type configJSON struct {
+	Files        []string          `json:"files"`
+	Environments []envJSON         `json:"environments"`
+	Patterns     []entryJSON       `json:"patterns"`
+	Ignore       []entryJSON       `json:"ignore"`
+	Layouts      []entryJSON       `json:"layouts"`
+	Rewrites     []rewriteJSON     `json:"rewrites"`
+	Settings     map[string]any    `json:"settings"`
+	Origins      map[string]string `json:"setting_origins"`
+}
+
+type envJSON struct {
+	Name        string            `json:"name"`
+	Origin      string            `json:"origin"`
+	Dirs        []string          `json:"dirs"`
+	Aliases     []string          `json:"aliases"`
+	Description string            `json:"description,omitempty"`
+	Meta        map[string]string `json:"meta,omitempty"`
+}
+
+type entryJSON struct {
+	Value  string `json:"value"`
+	Origin string `json:"origin"`
+}
+
+type rewriteJSON struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Match   string `json:"match"`
+	Replace string `json:"replace"`
+	Origin  string `json:"origin"`
+}
+
+func newConfigJSON(cfg *envpath.Config) configJSON {
+	out := configJSON{
+		Files:        append([]string{}, cfg.Files...),
+		Environments: make([]envJSON, len(cfg.Envs)),
+		Patterns:     []entryJSON{},
+		Ignore:       []entryJSON{},
+		Layouts:      []entryJSON{},
+		Rewrites:     []rewriteJSON{},
+		Settings: map[string]any{
+			"root_markers":     rootMarkersValue(cfg),
+			"env_marker":       envMarkerValue(cfg),
+			"case":             caseValue(cfg),
+			"occurrence":       occurrenceValue(cfg),
+			"token_boundaries": tokenBoundariesValue(cfg),
+		},
+		Origins: map[string]string{
+			"root_markers":     cfg.RootMarkers.Origin.String(),
+			"env_marker":       cfg.EnvMarker.Origin.String(),
+			"case":             cfg.Case.Origin.String(),
+			"occurrence":       cfg.Occurrence.Origin.String(),
+			"token_boundaries": cfg.TokenBoundaries.Origin.String(),
+		},
+	}
+	for i, env := range cfg.Envs {
+		entry := envJSON{
+			Name:        env.Name,
+			Origin:      env.Origin.String(),
+			Dirs:        env.DirNames(),
+			Aliases:     make([]string, len(env.Aliases)),
+			Description: env.Description.Value,
+		}
+		for j, alias := range env.Aliases {
+			entry.Aliases[j] = alias.Name
+		}
+		if len(env.Meta) > 0 {
+			entry.Meta = make(map[string]string, len(env.Meta))
+			for key, meta := range env.Meta {
+				entry.Meta[key] = meta.Value
+			}
+		}
+		out.Environments[i] = entry
+	}
+	for _, pattern := range cfg.Patterns {
+		out.Patterns = append(out.Patterns, entryJSON{Value: pattern.Raw, Origin: pattern.Origin.String()})
+	}
+	for _, rule := range cfg.Ignore {
+		out.Ignore = append(out.Ignore, entryJSON{Value: rule.Raw, Origin: rule.Origin.String()})
+	}
+	for _, layout := range cfg.Layouts {
+		out.Layouts = append(out.Layouts, entryJSON{Value: layout.Template, Origin: layout.Origin.String()})
+	}
+	for _, rule := range cfg.Rules {
+		out.Rewrites = append(out.Rewrites, rewriteJSON{
+			From:    envOrAny(rule.From),
+			To:      envOrAny(rule.To),
+			Match:   rule.Match,
+			Replace: rule.Replace,
+			Origin:  rule.Origin.String(),
+		})
+	}
+	return out
+}
+
+// printIssues writes one line per issue and reports whether there were any.
+func printIssues(w io.Writer, issues []envpath.Issue, homeDir string) bool {
+	if len(issues) == 0 {
+		fmt.Fprintln(w, "No problems found.")
+		return false
+	}
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s: %s\n", formatOrigin(issue.Origin, homeDir), issue.Message)
+	}
+	return true
+}
+
+func formatOrigin(origin envpath.Origin, homeDir string) string {
+	if origin.File != "" {
+		origin.File = displayPath(origin.File, homeDir)
+	}
+	return origin.String()
+}
+
+func quoteList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestPrintConfigShowsOrigins(t *testing.T) {
+	home := "/home/example"
+	cfg := &envpath.Config{
+		Files: []string{home + "/.cenvrc"},
+		Envs: []envpath.Env{
+			{Name: "dev"},
+			{
+				Name:    "staging",
+				Origin:  envpath.Origin{File: home + "/.cenvrc", Line: 2},
+				Aliases: []envpath.Alias{{Name: "stg", Origin: envpath.Origin{Variable: "CENV_ENVIRONMENTS"}}},
+				Meta: map[string]envpath.Setting[string]{
+					"owner": {Value: "platform", Origin: envpath.Origin{File: home + "/.config/changeenv/config.toml", Line: 4}},
+				},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	printConfig(&buf, cfg, home)
+	out := buf.String()
+
+	for _, want := range []string{
+		"  ~/.cenvrc\n",
+		"  dev                      built-in\n",
+		"  staging                  ~/.cenvrc:2\n",
+		"    alias stg              $CENV_ENVIRONMENTS\n",
+		"    meta owner=\"platform\"  ~/.config/changeenv/config.toml:4\n",
+		"  root_markers = [\".git\"]              built-in\n",
+		"  occurrence = \"innermost-under-root\"  built-in\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestPrintIssuesReportsProblems(t *testing.T) {
+	var buf bytes.Buffer
+	found := printIssues(&buf, []envpath.Issue{{
+		Origin:  envpath.Origin{File: "/home/example/.cenvrc", Line: 3},
+		Message: `duplicate environment "prod", already defined at built-in`,
+	}}, "/home/example")
+	if !found {
+		t.Fatalf("expected printIssues to report problems")
+	}
+	want := "~/.cenvrc:3: duplicate environment \"prod\", already defined at built-in\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	if printIssues(&buf, nil, "") {
+		t.Fatalf("expected no problems for empty issue list")
+	}
+}
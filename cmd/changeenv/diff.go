@@ -0,0 +1,285 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"envchanger/internal/envpath"
+	"envchanger/internal/textdiff"
+)
+
+// envToken replaces environment names in lines compared with --normalize-env.
+const envToken = "${env}"
+
+func newDiffCommand(opts *envpath.Options, output *outputFormat) *cobra.Command {
+	var (
+		stat         bool
+		normalizeEnv bool
+		context      int
+	)
+
+	cmd := &cobra.Command{
+		Use:   "diff <env> [path]",
+		Short: "Show a unified diff between a file or directory and its counterpart in another environment.",
+		Long: `Compare path (default: the current directory) with its counterpart in env,
+recursively, and print a unified diff. Files present on one side only are
+shown as added or removed. With --normalize-env, lines that differ only by the
+names or directory names of the two environments are treated as equal.
+
+Exits with status 9 when the trees differ.`,
+		Args:          usageArgs(cobra.RangeArgs(1, 2)),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(cmd, *output, outputText, outputJSON); err != nil {
+				return err
+			}
+			if context < 0 {
+				return newUsageError(cmd, "--unified must not be negative")
+			}
+			path, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("determine current directory: %w", err)
+			}
+			if len(args) > 1 {
+				if !filepath.IsAbs(args[1]) {
+					path = filepath.Join(path, args[1])
+				} else {
+					path = args[1]
+				}
+			}
+
+			result, pairs, err := envpath.CounterpartFiles(path, args[0], *opts)
+			if err != nil {
+				return err
+			}
+			cmp := fileComparison{
+				from:    result.EnvFrom,
+				to:      result.EnvTo,
+				subpath: filepath.ToSlash(result.Subpath),
+				context: context,
+			}
+			if normalizeEnv {
+				cfg, err := envpath.LoadConfig(path, opts.ConfigPath)
+				if err != nil {
+					return err
+				}
+				cmp.config = cfg
+				cmp.envNames = append(cfg.EnvTokens(result.EnvFrom), cfg.EnvTokens(result.EnvTo)...)
+			}
+			diffs, err := cmp.diffAll(pairs)
+			if err != nil {
+				return err
+			}
+
+			w := cmd.OutOrStdout()
+			if *output == outputJSON {
+				if err := writeJSON(w, newDiffJSON(result, diffs, !stat)); err != nil {
+					return err
+				}
+			} else if stat {
+				printDiffStat(w, diffs)
+			} else {
+				for _, diff := range diffs {
+					fmt.Fprint(w, diff.text)
+				}
+			}
+			if len(diffs) > 0 {
+				return &exitStatusError{code: exitDifferences}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&stat, "stat", false, "print a summary of changed files instead of the diff")
+	cmd.Flags().BoolVar(&normalizeEnv, "normalize-env", false, "ignore lines that differ only by the environment names or directory names")
+	cmd.Flags().IntVarP(&context, "unified", "U", 3, "number of context lines around each change")
+
+	return cmd
+}
+
+// fileDiff is the difference between a file and its counterpart.
+type fileDiff struct {
+	path string
+	// status is "modified", "added" when only the target has the file, or
+	// "removed" when only the source has it.
+	status     string
+	text       string
+	insertions int
+	deletions  int
+	binary     bool
+}
+
+// fileComparison compares files between the environments from and to.
+type fileComparison struct {
+	from, to string
+	// subpath is the slash-separated location of the compared directory
+	// below the environment directory, used in file headers.
+	subpath string
+	context int
+	// envNames, when set, are replaced by envToken before lines are compared,
+	// matched according to the case mode of config.
+	config   *envpath.Config
+	envNames []string
+}
+
+// diffAll compares every pair and returns the files that differ.
+func (c fileComparison) diffAll(pairs []envpath.FilePair) ([]fileDiff, error) {
+	var diffs []fileDiff
+	for _, pair := range pairs {
+		diff, err := c.diff(pair)
+		if err != nil {
+			return nil, err
+		}
+		if diff != nil {
+			diffs = append(diffs, *diff)
+		}
+	}
+	return diffs, nil
+}
+
+// diff compares one pair and returns nil when both sides are equal.
+func (c fileComparison) diff(pair envpath.FilePair) (*fileDiff, error) {
+	a, err := readSide(pair.From)
+	if err != nil {
+		return nil, err
+	}
+	b, err := readSide(pair.To)
+	if err != nil {
+		return nil, err
+	}
+	if pair.From != "" && pair.To != "" && bytes.Equal(a, b) {
+		return nil, nil
+	}
+
+	name := path.Join(c.subpath, pair.Path)
+	fromName, toName := c.from+"/"+name, c.to+"/"+name
+	status := "modified"
+	switch {
+	case pair.From == "":
+		status = "added"
+	case pair.To == "":
+		status = "removed"
+	}
+	if bytes.IndexByte(a, 0) >= 0 || bytes.IndexByte(b, 0) >= 0 {
+		return &fileDiff{
+			path:   pair.Path,
+			status: status,
+			text:   fmt.Sprintf("Binary files %s and %s differ\n", fromName, toName),
+			binary: true,
+		}, nil
+	}
+	if pair.From == "" {
+		fromName = "/dev/null"
+	}
+	if pair.To == "" {
+		toName = "/dev/null"
+	}
+
+	aLines, bLines := textdiff.SplitLines(string(a)), textdiff.SplitLines(string(b))
+	var equal func(x, y string) bool
+	if len(c.envNames) > 0 {
+		equal = func(x, y string) bool {
+			return c.config.ReplaceEnvTokens(x, c.envNames, envToken) == c.config.ReplaceEnvTokens(y, c.envNames, envToken)
+		}
+	}
+	edits := textdiff.Lines(aLines, bLines, equal)
+	if !textdiff.Changed(edits) && pair.From != "" && pair.To != "" {
+		return nil, nil
+	}
+	insertions, deletions := textdiff.Stat(edits)
+	text := textdiff.Unified(fromName, toName, aLines, bLines, edits, c.context)
+	if text == "" {
+		// An empty file present on one side only.
+		text = fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName)
+	}
+	return &fileDiff{path: pair.Path, status: status, text: text, insertions: insertions, deletions: deletions}, nil
+}
+
+// readSide reads a compared file; a missing side reads as empty.
+func readSide(path string) ([]byte, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return data, nil
+}
+
+type diffJSON struct {
+	Source  string         `json:"source"`
+	Target  string         `json:"target"`
+	EnvFrom string         `json:"env_from"`
+	EnvTo   string         `json:"env_to"`
+	Files   []fileDiffJSON `json:"files"`
+}
+
+type fileDiffJSON struct {
+	Path       string `json:"path"`
+	Status     string `json:"status"`
+	Binary     bool   `json:"binary"`
+	Insertions int    `json:"insertions"`
+	Deletions  int    `json:"deletions"`
+	Diff       string `json:"diff,omitempty"`
+}
+
+// newDiffJSON returns the JSON form of diffs, with the unified diff text
+// when withText is set.
+func newDiffJSON(result *envpath.Result, diffs []fileDiff, withText bool) diffJSON {
+	out := diffJSON{
+		Source:  result.Source,
+		Target:  result.Target,
+		EnvFrom: result.EnvFrom,
+		EnvTo:   result.EnvTo,
+		Files:   make([]fileDiffJSON, len(diffs)),
+	}
+	for i, diff := range diffs {
+		out.Files[i] = fileDiffJSON{
+			Path:       diff.path,
+			Status:     diff.status,
+			Binary:     diff.binary,
+			Insertions: diff.insertions,
+			Deletions:  diff.deletions,
+		}
+		if withText {
+			out.Files[i].Diff = diff.text
+		}
+	}
+	return out
+}
+
+// printDiffStat writes one line per changed file followed by the totals.
+func printDiffStat(w io.Writer, diffs []fileDiff) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	insertions, deletions := 0, 0
+	for _, diff := range diffs {
+		if diff.binary {
+			fmt.Fprintf(tw, "%s\t| binary\n", diff.path)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t| +%d -%d\n", diff.path, diff.insertions, diff.deletions)
+		insertions += diff.insertions
+		deletions += diff.deletions
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "%d %s changed, %d %s(+), %d %s(-)\n",
+		len(diffs), plural(len(diffs), "file", "files"),
+		insertions, plural(insertions, "insertion", "insertions"),
+		deletions, plural(deletions, "deletion", "deletions"))
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
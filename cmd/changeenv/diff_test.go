@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestFileComparisonNormalizesEnvNames(t *testing.T) {
+	dir := t.TempDir()
+	from, to := filepath.Join(dir, "dev.yaml"), filepath.Join(dir, "prod.yaml")
+	mustWrite(t, from, "name: app-dev\nreplicas: 1\n")
+	mustWrite(t, to, "name: app-prod\nreplicas: 3\n")
+	pair := envpath.FilePair{Path: "values.yaml", From: from, To: to}
+
+	cmp := fileComparison{from: "dev", to: "prod", subpath: "app", context: 3}
+	diff, err := cmp.diff(pair)
+	if err != nil {
+		t.Fatalf("diff returned error: %v", err)
+	}
+	if diff == nil || diff.insertions != 2 || diff.deletions != 2 {
+		t.Fatalf("expected both lines to differ, got %+v", diff)
+	}
+	if !strings.HasPrefix(diff.text, "--- dev/app/values.yaml\n+++ prod/app/values.yaml\n") {
+		t.Fatalf("unexpected header in %q", diff.text)
+	}
+
+	cmp.config, cmp.envNames = &envpath.Config{}, []string{"dev", "prod"}
+	diff, err = cmp.diff(pair)
+	if err != nil {
+		t.Fatalf("diff returned error: %v", err)
+	}
+	if diff == nil || diff.insertions != 1 || diff.deletions != 1 || strings.Contains(diff.text, "-name:") {
+		t.Fatalf("expected only replicas to differ, got %+v", diff)
+	}
+
+	mustWrite(t, to, "name: app-prod\nreplicas: 1\n")
+	if diff, err := cmp.diff(pair); err != nil || diff != nil {
+		t.Fatalf("expected no difference after normalisation, got %+v, %v", diff, err)
+	}
+}
+
+func TestDiffNormalizesEnvDirectoryNames(t *testing.T) {
+	repo := setupRepo(t, "[env.dev]\ndirs = [\"development\"]\n\n[env.prod]\ndirs = [\"production\"]\n")
+	mustWrite(t, filepath.Join(repo, "development", "app", "values.yaml"), "host: db.development.internal\n")
+	mustWrite(t, filepath.Join(repo, "production", "app", "values.yaml"), "host: db.production.internal\n")
+	t.Chdir(filepath.Join(repo, "development", "app"))
+
+	for _, tt := range []struct {
+		args    []string
+		differs bool
+	}{
+		{[]string{"prod"}, true},
+		{[]string{"prod", "--normalize-env"}, false},
+	} {
+		output := outputText
+		cmd := newDiffCommand(&envpath.Options{}, &output)
+		cmd.SetArgs(tt.args)
+		cmd.SetOut(io.Discard)
+		if err := cmd.Execute(); (err != nil) != tt.differs {
+			t.Fatalf("%q: expected differences %v, got %v", tt.args, tt.differs, err)
+		}
+	}
+}
+
+func TestFileComparisonOneSidedFiles(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "alerts.yaml")
+	mustWrite(t, file, "rule: x\n")
+
+	cmp := fileComparison{from: "dev", to: "prod", context: 3}
+	diff, err := cmp.diff(envpath.FilePair{Path: "alerts.yaml", To: file})
+	if err != nil {
+		t.Fatalf("diff returned error: %v", err)
+	}
+	if want := "--- /dev/null\n+++ prod/alerts.yaml\n@@ -0,0 +1 @@\n+rule: x\n"; diff == nil || diff.text != want {
+		t.Fatalf("expected %q, got %+v", want, diff)
+	}
+}
+
+func TestPrintDiffStat(t *testing.T) {
+	var buf bytes.Buffer
+	printDiffStat(&buf, []fileDiff{
+		{path: "app/values.yaml", insertions: 2, deletions: 1},
+		{path: "logo.png", binary: true},
+	})
+	want := "app/values.yaml  | +2 -1\nlogo.png         | binary\n2 files changed, 2 insertions(+), 1 deletion(-)\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"envchanger/internal/envpath"
+)
+
+func newDriftCommand(opts *envpath.Options, output *outputFormat) *cobra.Command {
+	var (
+		baseline       string
+		updateBaseline bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "Report files that exist in one environment tree but not in another.",
+		Long: `Compare file presence across the environment trees next to the current
+environment. Files missing from some tree are allowed only when listed in the
+baseline file (default .cenv-drift in the directory holding the environment
+directories) as "<env> <path>" lines. Exits with status 9 when other files
+are missing, so it can gate CI.
+
+Run with --update-baseline to rewrite the baseline from the current trees.`,
+		Args:          validateNoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(cmd, *output, outputText, outputJSON); err != nil {
+				return err
+			}
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("determine current directory: %w", err)
+			}
+			report, err := envpath.Drift(cwd, *opts)
+			if err != nil {
+				return err
+			}
+			if baseline == "" {
+				baseline = filepath.Join(report.BaseDir, envpath.DriftBaselineName)
+			}
+
+			if updateBaseline {
+				var buf bytes.Buffer
+				if err := report.WriteBaseline(&buf); err != nil {
+					return err
+				}
+				if err := os.WriteFile(baseline, buf.Bytes(), 0o644); err != nil {
+					return fmt.Errorf("write drift baseline: %w", err)
+				}
+				fmt.Fprintf(cmd.ErrOrStderr(), "changeenv: wrote %d entries to %s\n", len(report.Missing), baseline)
+				return nil
+			}
+
+			allowed, err := envpath.ReadDriftBaseline(baseline)
+			if err != nil {
+				return err
+			}
+			unexpected, stale := report.Unexpected(allowed)
+			if *output == outputJSON {
+				out := driftJSON{
+					Baseline:   baseline,
+					BaseDir:    report.BaseDir,
+					Envs:       report.Envs,
+					Unexpected: newDriftEntriesJSON(unexpected),
+					Stale:      newDriftEntriesJSON(stale),
+				}
+				if err := writeJSON(cmd.OutOrStdout(), out); err != nil {
+					return err
+				}
+				if len(unexpected) > 0 {
+					return &exitStatusError{code: exitDifferences}
+				}
+				return nil
+			}
+			for _, entry := range stale {
+				fmt.Fprintf(cmd.ErrOrStderr(), "changeenv: baseline entry no longer drifts: %s %s\n", entry.Env, entry.Path)
+			}
+			if printDrift(cmd.OutOrStdout(), unexpected) {
+				return &exitStatusError{code: exitDifferences}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&baseline, "baseline", "", "baseline file listing allowed drift (default .cenv-drift next to the environment directories)")
+	cmd.Flags().BoolVar(&updateBaseline, "update-baseline", false, "rewrite the baseline file from the current trees")
+
+	return cmd
+}
+
+type driftJSON struct {
+	Baseline   string           `json:"baseline"`
+	BaseDir    string           `json:"base_dir"`
+	Envs       []string         `json:"envs"`
+	Unexpected []driftEntryJSON `json:"unexpected"`
+	Stale      []driftEntryJSON `json:"stale"`
+}
+
+type driftEntryJSON struct {
+	Env    string `json:"env"`
+	Path   string `json:"path"`
+	Source string `json:"source,omitempty"`
+}
+
+func newDriftEntriesJSON(entries []envpath.DriftEntry) []driftEntryJSON {
+	out := make([]driftEntryJSON, len(entries))
+	for i, entry := range entries {
+		out[i] = driftEntryJSON{Env: entry.Env, Path: entry.Path, Source: entry.Source}
+	}
+	return out
+}
+
+// printDrift writes one line per missing file and reports whether there were
+// any.
+func printDrift(w io.Writer, missing []envpath.DriftEntry) bool {
+	if len(missing) == 0 {
+		fmt.Fprintln(w, "No drift beyond the baseline.")
+		return false
+	}
+	for _, entry := range missing {
+		fmt.Fprintf(w, "%s: missing %s (present in %s)\n", entry.Env, entry.Path, entry.Source)
+	}
+	return true
+}
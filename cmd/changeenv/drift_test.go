@@ -0,0 +1,24 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestPrintDrift(t *testing.T) {
+	var buf bytes.Buffer
+	if printDrift(&buf, nil) || buf.String() != "No drift beyond the baseline.\n" {
+		t.Fatalf("unexpected output for no drift: %q", buf.String())
+	}
+
+	buf.Reset()
+	missing := []envpath.DriftEntry{{Env: "prod", Path: "app/debug.yaml", Source: "dev"}}
+	if !printDrift(&buf, missing) {
+		t.Fatalf("expected drift to be reported")
+	}
+	if want := "prod: missing app/debug.yaml (present in dev)\n"; buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"envchanger/internal/envpath"
+)
+
+// printTrace writes the steps of a resolution recorded by envpath.Explain.
+func printTrace(w io.Writer, trace *envpath.Trace, homeDir string) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "input:\t%s\n", trace.Input)
+	fmt.Fprintf(tw, "cleaned:\t%s\n", valueOrNone(trace.Cleaned))
+	if trace.Parts != nil {
+		fmt.Fprintf(tw, "split:\tvolume=%q absolute=%t parts=%q\n", trace.Volume, trace.Absolute, trace.Parts)
+		markers := strings.Join(trace.RootMarkers, ", ")
+		if trace.Root != "" {
+			fmt.Fprintf(tw, "root:\t%s  (markers: %s)\n", trace.Root, markers)
+		} else {
+			fmt.Fprintf(tw, "root:\t(none; no %s found, searching the whole path)\n", markers)
+		}
+	}
+	if trace.TargetEnv != "" {
+		fmt.Fprintf(tw, "target:\t%s  (%q: %s; directory %q)\n", trace.TargetEnv, trace.Target, trace.TargetReason, trace.TargetDir)
+	}
+	if trace.Parts != nil {
+		if trace.Layout != nil {
+			fmt.Fprintf(tw, "layout:\t%s  (%s)\n", trace.Layout.Template, formatOrigin(trace.Layout.Origin, homeDir))
+		} else {
+			fmt.Fprintf(tw, "layout:\t%s\n", "(none)")
+		}
+	}
+	tw.Flush()
+
+	if len(trace.Segments) > 0 {
+		fmt.Fprintf(w, "occurrence: %s\n", trace.Occurrence)
+		fmt.Fprintln(w, "segments:")
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, check := range trace.Segments {
+			mark := ""
+			if check.Index == trace.EnvIndex {
+				mark = "  <- chosen"
+			} else if isCandidate(trace.Candidates, check.Index) {
+				mark = "  <- candidate"
+			}
+			fmt.Fprintf(tw, "  [%d]\t%s\t%s%s\n", check.Index, check.Segment, check.Reason, mark)
+		}
+		tw.Flush()
+		if trace.EnvIndex >= 0 {
+			fmt.Fprintf(w, "chosen: index %d, spanning %d segment(s)\n", trace.EnvIndex, trace.EnvLen)
+		} else {
+			fmt.Fprintln(w, "chosen: (none)")
+		}
+	}
+
+	if len(trace.Steps) > 0 {
+		fmt.Fprintln(w, "steps:")
+		for _, step := range trace.Steps {
+			fmt.Fprintf(w, "  %s\n", step)
+		}
+	}
+	if trace.Assembled != "" {
+		fmt.Fprintln(w, "rewrites:")
+		if len(trace.Rewrites) == 0 {
+			fmt.Fprintln(w, "  (none)")
+		}
+		for _, rewrite := range trace.Rewrites {
+			fmt.Fprintf(w, "  %s: %q -> %q\n", formatOrigin(rewrite.Rule.Origin, homeDir), rewrite.Before, rewrite.After)
+		}
+	}
+	if len(trace.Checks) > 0 {
+		fmt.Fprintln(w, "existence checks:")
+		tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		for _, check := range trace.Checks {
+			state := "missing"
+			if check.Exists {
+				state = "exists"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\n", state, check.Path)
+		}
+		tw.Flush()
+	}
+
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if trace.Assembled != "" {
+		fmt.Fprintf(tw, "assembled:\t%s\n", trace.Assembled)
+	}
+	if trace.Result != nil {
+		fmt.Fprintf(tw, "result:\t%s\n", trace.Result.Target)
+	} else {
+		fmt.Fprintf(tw, "result:\t%s\n", "(failed)")
+	}
+	tw.Flush()
+}
+
+func isCandidate(candidates []envpath.Candidate, index int) bool {
+	for _, candidate := range candidates {
+		if candidate.Index == index {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"envchanger/internal/envpath"
+)
+
+func newListCommand(opts *envpath.Options, output *outputFormat) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List the environments that can be switched to from the current directory.",
+		Long: `List the configured environments and the directories next to the current
+environment root. The current environment is marked with ">", and each entry
+shows whether the exact counterpart of the current directory exists there.`,
+		Args:          validateNoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(cmd, *output, outputText, outputJSON); err != nil {
+				return err
+			}
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("determine current directory: %w", err)
+			}
+			list, err := envpath.Counterparts(cwd, *opts)
+			if err != nil {
+				return err
+			}
+			if *output == outputJSON {
+				return printCounterpartsJSON(cmd.OutOrStdout(), list)
+			}
+			printCounterparts(cmd.OutOrStdout(), list)
+			return nil
+		},
+	}
+}
+
+func printCounterparts(w io.Writer, list []envpath.Counterpart) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, counterpart := range list {
+		current := " "
+		if counterpart.Current {
+			current = ">"
+		}
+		source := "directory"
+		if counterpart.Configured {
+			source = "configured"
+		}
+		switch {
+		case counterpart.Err != nil:
+			fmt.Fprintf(tw, "%s %s\t%s\t%s\t%v\n", current, counterpart.Env, source, "error", counterpart.Err)
+		case counterpart.Exists:
+			fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\n", current, counterpart.Env, source, "exists", counterpart.Path)
+		default:
+			fmt.Fprintf(tw, "%s %s\t%s\t%s\t%s\n", current, counterpart.Env, source, "missing", counterpart.Path)
+		}
+	}
+	tw.Flush()
+}
+
+type counterpartJSON struct {
+	Env        string `json:"env"`
+	Dir        string `json:"dir"`
+	Path       string `json:"path,omitempty"`
+	Current    bool   `json:"current"`
+	Configured bool   `json:"configured"`
+	Exists     bool   `json:"exists"`
+	Error      string `json:"error,omitempty"`
+}
+
+func printCounterpartsJSON(w io.Writer, list []envpath.Counterpart) error {
+	out := make([]counterpartJSON, len(list))
+	for i, counterpart := range list {
+		out[i] = counterpartJSON{
+			Env:        counterpart.Env,
+			Dir:        counterpart.Dir,
+			Path:       counterpart.Path,
+			Current:    counterpart.Current,
+			Configured: counterpart.Configured,
+			Exists:     counterpart.Exists,
+		}
+		if counterpart.Err != nil {
+			out[i].Error = counterpart.Err.Error()
+		}
+	}
+	return writeJSON(w, out)
+}
@@ -3,7 +3,9 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -24,47 +26,149 @@ func newUsageError(cmd *cobra.Command, message string) error {
 	return &usageError{cmd: cmd, message: message}
 }
 
+// exitStatusError terminates the process with code without printing an
+// additional message; the command has already reported what happened.
+type exitStatusError struct {
+	code int
+}
+
+func (e *exitStatusError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// Exit statuses. Commands that compare trees or check the configuration exit
+// with exitDifferences when they find differences or problems, so that CI can
+// tell findings from failures to run.
+const (
+	exitFailure          = 1
+	exitUsage            = 2
+	exitApproximate      = 3
+	exitAmbiguousTarget  = 4
+	exitNotInEnv         = 5
+	exitUnknownTarget    = 6
+	exitAmbiguousSegment = 7
+	exitTargetMissing    = 8
+	exitDifferences      = 9
+)
+
 func main() {
-	rootCmd := newRootCommand()
-
-	if err := rootCmd.Execute(); err != nil {
-		var uErr *usageError
-		if errors.As(err, &uErr) {
-			uErr.cmd.SetErr(os.Stderr)
-			uErr.cmd.SetOut(os.Stderr)
-			if uErr.message != "" {
-				uErr.cmd.PrintErrln(uErr.message)
-			}
-			_ = uErr.cmd.Usage()
-			os.Exit(2)
+	os.Exit(run(newRootCommand(), os.Stdout, os.Stderr))
+}
+
+// run executes cmd and returns the exit status. Failures are reported on
+// stderr, or as a JSON error object on stdout with --output json.
+func run(cmd *cobra.Command, stdout, stderr io.Writer) int {
+	err := cmd.Execute()
+	if err == nil {
+		return 0
+	}
+	var sErr *exitStatusError
+	if errors.As(err, &sErr) {
+		return sErr.code
+	}
+
+	report, code := classifyError(err)
+	if flag := cmd.PersistentFlags().Lookup("output"); flag != nil && flag.Value.String() == string(outputJSON) {
+		if err := writeJSON(stdout, report); err != nil {
+			fmt.Fprintf(stderr, "changeenv: %v\n", err)
 		}
-		exitWithError(err)
+		return code
 	}
+	var uErr *usageError
+	if errors.As(err, &uErr) {
+		uErr.cmd.SetErr(stderr)
+		uErr.cmd.SetOut(stderr)
+		if uErr.message != "" {
+			uErr.cmd.PrintErrln(uErr.message)
+		}
+		_ = uErr.cmd.Usage()
+		return code
+	}
+	fmt.Fprintf(stderr, "changeenv: %v\n", err)
+	if hint := didYouMean(report); hint != "" {
+		fmt.Fprintf(stderr, "changeenv: %s\n", hint)
+	}
+	return code
 }
 
 func newRootCommand() *cobra.Command {
+	var (
+		opts       envpath.Options
+		showRoot   bool
+		explain    bool
+		sets       []string
+		occurrence string
+		output     outputFormat
+	)
+
 	cmd := &cobra.Command{
-		Use:   "changeenv [target-env]",
+		Use:   "changeenv [target-env] [--set dimension=value]...",
 		Short: "Switch to the same relative directory in another environment tree.",
 		Long: `Switch the current working directory to the same relative location in another environment tree.
 
 Examples:
   changeenv prod
+  changeenv p        # unique prefix of prod
 
 To change shell directories directly, wrap with a shell function:
   cenv() { cd "$(changeenv "$1")"; }
+
+With --nearest, a missing counterpart falls back to its deepest existing
+ancestor in the target environment. The dropped segments are reported on
+stderr and the command exits with status 3 instead of 0.
+
+Environment segments are only searched below the repository root, found by
+walking up to the nearest directory containing one of the root markers
+(default .git; override with --root-marker or $CENV_ROOT_MARKERS).
+
+When a layout template from the config matches the current directory, any of
+its dimensions can be switched with --set, for example:
+  changeenv --set region=eu-west-1 --set env=prod
+Run "changeenv where" to see the dimensions parsed from the current directory.
+
+When the path contains several environment segments, the outermost one below
+the repository root is switched and a warning is printed. Choose another
+segment with --occurrence or the occurrence config setting.
+
+Pass --explain to print how the path was split, why each segment did or did
+not match an environment, the rules applied and the directories checked.
+
+With --output json, the result is printed as a JSON object and failures as a
+{"code", "message", "candidates"} object on stdout.
 `,
 		Args:          cobra.ArbitraryArgs,
 		SilenceUsage:  true,
 		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if occurrence == "" {
+				return nil
+			}
+			policy, err := envpath.ParseOccurrence(occurrence)
+			if err != nil {
+				return newUsageError(cmd, err.Error())
+			}
+			opts.Occurrence = policy
+			return nil
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) < 1 {
+			if err := checkOutput(cmd, output, outputText, outputJSON); err != nil {
+				return err
+			}
+			set, err := parseSets(sets)
+			if err != nil {
+				return newUsageError(cmd, err.Error())
+			}
+			opts.Set = set
+			if len(args) < 1 && len(set) == 0 {
 				return newUsageError(cmd, "target environment argument is required")
 			}
 
-			targetEnv := strings.TrimSpace(args[0])
-			if targetEnv == "" {
-				return newUsageError(cmd, "target environment must not be empty")
+			targetEnv := ""
+			if len(args) > 0 {
+				targetEnv = strings.TrimSpace(args[0])
+				if targetEnv == "" {
+					return newUsageError(cmd, "target environment must not be empty")
+				}
 			}
 
 			cwd, err := os.Getwd()
@@ -72,22 +176,71 @@ To change shell directories directly, wrap with a shell function:
 				return fmt.Errorf("determine current directory: %w", err)
 			}
 
-			targetPath, err := envpath.Switch(cwd, targetEnv)
+			var result *envpath.Result
+			if explain {
+				var trace *envpath.Trace
+				trace, err = envpath.Explain(cwd, targetEnv, opts)
+				homeDir, _ := os.UserHomeDir()
+				printTrace(cmd.ErrOrStderr(), trace, homeDir)
+				result = trace.Result
+			} else {
+				result, err = envpath.Resolve(cwd, targetEnv, opts)
+			}
 			if err != nil {
 				return err
 			}
+			if showRoot {
+				if result.Root != "" {
+					fmt.Fprintf(cmd.ErrOrStderr(), "changeenv: repository root: %s\n", result.Root)
+				} else {
+					fmt.Fprintln(cmd.ErrOrStderr(), "changeenv: no repository root found; searched the whole path")
+				}
+			}
 
-			fmt.Fprintln(cmd.OutOrStdout(), targetPath)
+			for _, warning := range result.Warnings {
+				fmt.Fprintf(cmd.ErrOrStderr(), "changeenv: warning: %s\n", warning)
+			}
+			if output == outputJSON {
+				if err := writeJSON(cmd.OutOrStdout(), newResultJSON(result)); err != nil {
+					return err
+				}
+			} else {
+				fmt.Fprintln(cmd.OutOrStdout(), result.Target)
+			}
+			if !result.Exact() {
+				fmt.Fprintf(cmd.ErrOrStderr(), "changeenv: exact match not found; dropped %s\n", strings.Join(result.Dropped, string(filepath.Separator)))
+				return &exitStatusError{code: exitApproximate}
+			}
 			return nil
 		},
 	}
 
-	cmd.AddCommand(newConfigureCommand())
+	cmd.Flags().BoolVarP(&opts.Nearest, "nearest", "n", false, "fall back to the deepest existing ancestor when the target directory is missing")
+	cmd.Flags().BoolVar(&showRoot, "show-root", false, "print the repository root used for the environment search to stderr")
+	cmd.Flags().BoolVar(&explain, "explain", false, "print each step of the resolution to stderr")
+	cmd.Flags().StringArrayVar(&sets, "set", nil, "switch a layout dimension, as dimension=value (repeatable)")
+	cmd.PersistentFlags().StringArrayVar(&opts.RootMarkers, "root-marker", nil, "file or directory name marking the repository root (repeatable, default .git)")
+	cmd.PersistentFlags().StringVar(&occurrence, "occurrence", "", "which environment segment to switch when there are several: first, last, innermost-under-root or replace-all")
+	cmd.PersistentFlags().StringVar(&opts.ConfigPath, "config", "", "path to the config file (default $CENV_CONFIG or $XDG_CONFIG_HOME/changeenv/config.toml)")
+	cmd.PersistentFlags().StringVarP((*string)(&output), "output", "o", string(outputText), "output format: text or json; matrix also accepts csv and markdown")
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return newUsageError(cmd, err.Error())
+	})
+
+	cmd.AddCommand(newConfigureCommand(&output))
+	cmd.AddCommand(newConfigCommand(&opts, &output))
+	cmd.AddCommand(newWhereCommand(&opts, &output))
+	cmd.AddCommand(newListCommand(&opts, &output))
+	cmd.AddCommand(newMatrixCommand(&opts, &output))
+	cmd.AddCommand(newDriftCommand(&opts, &output))
+	cmd.AddCommand(newDiffCommand(&opts, &output))
+	cmd.AddCommand(newPathCommand(&opts, &output))
+	cmd.AddCommand(newMapCommand(&opts, &output))
 
 	return cmd
 }
 
-func newConfigureCommand() *cobra.Command {
+func newConfigureCommand(output *outputFormat) *cobra.Command {
 	var autoApply bool
 
 	cmd := &cobra.Command{
@@ -98,6 +251,9 @@ func newConfigureCommand() *cobra.Command {
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(cmd, *output, outputText); err != nil {
+				return err
+			}
 			return runConfigure(autoApply)
 		},
 	}
@@ -107,14 +263,40 @@ func newConfigureCommand() *cobra.Command {
 	return cmd
 }
 
+// parseSets turns dimension=value flags into a map.
+func parseSets(sets []string) (map[string]string, error) {
+	if len(sets) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]string, len(sets))
+	for _, set := range sets {
+		name, value, ok := strings.Cut(set, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if !ok || name == "" || value == "" {
+			return nil, fmt.Errorf("invalid --set %q, expected dimension=value", set)
+		}
+		if _, dup := parsed[name]; dup {
+			return nil, fmt.Errorf("dimension %q is set more than once", name)
+		}
+		parsed[name] = value
+	}
+	return parsed, nil
+}
+
 func validateNoArgs(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
-		return newUsageError(cmd, "configure does not accept positional arguments")
+		return newUsageError(cmd, cmd.CommandPath()+" does not accept positional arguments")
 	}
 	return nil
 }
 
-func exitWithError(err error) {
-	fmt.Fprintf(os.Stderr, "changeenv: %v\n", err)
-	os.Exit(1)
+// usageArgs wraps a cobra argument validator so that its failures are
+// reported as usage errors.
+func usageArgs(validate cobra.PositionalArgs) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := validate(cmd, args); err != nil {
+			return newUsageError(cmd, err.Error())
+		}
+		return nil
+	}
 }
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestParseSets(t *testing.T) {
+	got, err := parseSets([]string{"region=eu-west-1", " env = prod "})
+	if err != nil {
+		t.Fatalf("parseSets returned error: %v", err)
+	}
+	if got["region"] != "eu-west-1" || got["env"] != "prod" || len(got) != 2 {
+		t.Fatalf("unexpected result %v", got)
+	}
+
+	for _, invalid := range [][]string{{"region"}, {"=prod"}, {"env="}, {"env=a", "env=b"}} {
+		if _, err := parseSets(invalid); err == nil {
+			t.Fatalf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestPrintTraceMarksChosenSegmentAndRules(t *testing.T) {
+	var buf bytes.Buffer
+	rule := &envpath.Rule{Origin: envpath.Origin{File: "/home/example/repo/.cenv.toml", Line: 4}}
+	trace := &envpath.Trace{
+		Input:    "/repo/dev/services/app-dev",
+		Cleaned:  "/repo/dev/services/app-dev",
+		Absolute: true,
+		Parts:    []string{"repo", "dev", "services", "app-dev"},
+		Segments: []envpath.SegmentCheck{
+			{Index: 1, Segment: "dev", Matched: true, Env: "dev", Reason: "directory \"dev\" of environment \"dev\""},
+		},
+		EnvIndex:  1,
+		EnvLen:    1,
+		Rewrites:  []envpath.Rewrite{{Rule: rule, Before: "services/app-dev", After: "services/app"}},
+		Assembled: "/repo/prod/services/app",
+	}
+	printTrace(&buf, trace, "/home/example")
+
+	out := buf.String()
+	for _, want := range []string{
+		"  [1]  dev  directory \"dev\" of environment \"dev\"  <- chosen\n",
+		"  ~/repo/.cenv.toml:4: \"services/app-dev\" -> \"services/app\"\n",
+		"result:     (failed)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestPrintCounterparts(t *testing.T) {
+	list := []envpath.Counterpart{
+		{Env: "dev", Path: "/repo/dev/app", Current: true, Configured: true, Exists: true},
+		{Env: "staging", Path: "/repo/staging/app"},
+		{Env: "prod", Configured: true, Err: errors.New("boom")},
+	}
+
+	var buf bytes.Buffer
+	printCounterparts(&buf, list)
+	want := "> dev      configured  exists   /repo/dev/app\n" +
+		"  staging  directory   missing  /repo/staging/app\n" +
+		"  prod     configured  error    boom\n"
+	if buf.String() != want {
+		t.Fatalf("expected %q, got %q", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := printCounterpartsJSON(&buf, list[2:]); err != nil {
+		t.Fatalf("printCounterpartsJSON returned error: %v", err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if decoded[0]["env"] != "prod" || decoded[0]["error"] != "boom" || decoded[0]["exists"] != false {
+		t.Fatalf("unexpected JSON %v", decoded)
+	}
+}
+
+// isolateConfig points every configuration source at empty temporary
+// locations.
+func isolateConfig(t *testing.T) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("CENV_CONFIG", "")
+	t.Setenv("CENV_ENVIRONMENTS", "")
+	t.Setenv("CENV_ROOT_MARKERS", "")
+}
+
+// setupRepo isolates the configuration and returns a new repository root
+// containing dirs, whose .cenv.toml, unless config is empty, holds config.
+func setupRepo(t *testing.T, config string, dirs ...string) string {
+	t.Helper()
+	isolateConfig(t)
+	repo := t.TempDir()
+	for _, dir := range append([]string{".git"}, dirs...) {
+		if err := os.MkdirAll(filepath.Join(repo, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if config != "" {
+		mustWrite(t, filepath.Join(repo, ".cenv.toml"), config)
+	}
+	return repo
+}
+
+// mustWrite writes content to path, creating its parent directories.
+func mustWrite(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"envchanger/internal/envpath"
+)
+
+func newMapCommand(opts *envpath.Options, output *outputFormat) *cobra.Command {
+	var (
+		targetEnv string
+		null      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "map --to <env>",
+		Short: "Map paths read from stdin into another environment.",
+		Long: `Read one path per line from stdin and write its counterpart in the target
+environment to stdout, like "changeenv path" does for a single path. Relative
+paths are resolved against the current directory. A path that cannot be mapped
+produces an "error: ..." line in its place, so output lines stay aligned with
+input lines, and the command exits with status 1 once all paths are done.
+With --output json, every line is a JSON object instead: the resolved path, or
+{"source", "error"} for a path that cannot be mapped, one per line even with
+--null.
+
+The configuration is loaded once, for the current directory. With --null (-0),
+paths are read and written NUL-separated, as produced by "find -print0" or
+"git diff --name-only -z".`,
+		Args:          validateNoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(cmd, *output, outputText, outputJSON); err != nil {
+				return err
+			}
+			targetEnv = strings.TrimSpace(targetEnv)
+			if targetEnv == "" {
+				return newUsageError(cmd, "--to is required")
+			}
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("determine current directory: %w", err)
+			}
+			if opts.Config == nil {
+				cfg, err := envpath.LoadConfig(cwd, opts.ConfigPath)
+				if err != nil {
+					return err
+				}
+				opts.Config = cfg
+			}
+
+			failed, err := mapPaths(cmd.InOrStdin(), cmd.OutOrStdout(), cwd, targetEnv, *opts, null, *output)
+			if err != nil {
+				return err
+			}
+			if failed {
+				return &exitStatusError{code: exitFailure}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&targetEnv, "to", "", "target environment")
+	cmd.Flags().BoolVarP(&null, "null", "0", false, "read and write NUL-separated paths")
+
+	return cmd
+}
+
+// mapPaths maps every path read from r into targetEnv and writes one result
+// per path to w, as a JSON object per line when output is outputJSON. Blank
+// lines are copied through. It reports whether any path could not be mapped.
+func mapPaths(r io.Reader, w io.Writer, cwd, targetEnv string, opts envpath.Options, null bool, output outputFormat) (bool, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	separator := "\n"
+	if null {
+		scanner.Split(scanNull)
+		if output != outputJSON {
+			separator = "\x00"
+		}
+	}
+	bw := bufio.NewWriter(w)
+	failed := false
+	for scanner.Scan() {
+		path := scanner.Text()
+		if !null {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path == "" {
+			bw.WriteString(separator)
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cwd, path)
+		}
+		result, err := envpath.ResolveFile(path, targetEnv, opts)
+		if err != nil {
+			failed = true
+		}
+		if output == outputJSON {
+			var line []byte
+			if err != nil {
+				report, _ := classifyError(err)
+				line, _ = json.Marshal(mapErrorJSON{Source: path, Error: report})
+			} else {
+				line, _ = json.Marshal(newResultJSON(result))
+			}
+			bw.Write(line)
+			bw.WriteString(separator)
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(bw, "error: %v%s", err, separator)
+			continue
+		}
+		bw.WriteString(result.Target + separator)
+	}
+	if err := scanner.Err(); err != nil {
+		bw.Flush()
+		return failed, fmt.Errorf("read paths: %w", err)
+	}
+	return failed, bw.Flush()
+}
+
+// mapErrorJSON is the JSON line written for a path that cannot be mapped.
+type mapErrorJSON struct {
+	Source string    `json:"source"`
+	Error  errorJSON `json:"error"`
+}
+
+// scanNull is a bufio.SplitFunc for NUL-terminated records.
+func scanNull(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+// preloadedOptions returns options carrying the configuration of repo,
+// loaded once as the map command does.
+func preloadedOptions(t *testing.T, repo string) envpath.Options {
+	t.Helper()
+	cfg, err := envpath.LoadConfig(repo, "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	return envpath.Options{Config: cfg}
+}
+
+func TestMapPathsKeepsLinesAligned(t *testing.T) {
+	repo := setupRepo(t, "", "dev/app", "prod/app")
+	opts := preloadedOptions(t, repo)
+	input := "dev/app/values-dev.yaml\r\n\n" + filepath.Join(repo, "README.md") + "\ndev/app\n"
+
+	var out bytes.Buffer
+	failed, err := mapPaths(strings.NewReader(input), &out, repo, "prod", opts, false, outputText)
+	if err != nil {
+		t.Fatalf("mapPaths returned error: %v", err)
+	}
+	if !failed {
+		t.Fatalf("expected README.md to fail")
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 output lines, got %q", out.String())
+	}
+	if want := filepath.Join(repo, "prod", "app", "values-prod.yaml"); lines[0] != want {
+		t.Fatalf("expected %q, got %q", want, lines[0])
+	}
+	if lines[1] != "" || !strings.HasPrefix(lines[2], "error: ") {
+		t.Fatalf("expected a blank line and an error line, got %q", lines[1:3])
+	}
+	if want := filepath.Join(repo, "prod", "app"); lines[3] != want {
+		t.Fatalf("expected %q, got %q", want, lines[3])
+	}
+}
+
+func TestMapPathsNullSeparated(t *testing.T) {
+	repo := setupRepo(t, "", "dev/app", "prod/app")
+	opts := preloadedOptions(t, repo)
+
+	var out bytes.Buffer
+	failed, err := mapPaths(strings.NewReader("dev/app/a b.txt\x00dev"), &out, repo, "prod", opts, true, outputText)
+	if err != nil || failed {
+		t.Fatalf("mapPaths returned %v, %v", failed, err)
+	}
+	want := filepath.Join(repo, "prod", "app", "a b.txt") + "\x00" + filepath.Join(repo, "prod") + "\x00"
+	if out.String() != want {
+		t.Fatalf("expected %q, got %q", want, out.String())
+	}
+}
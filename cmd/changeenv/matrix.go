@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"envchanger/internal/envpath"
+)
+
+func newMatrixCommand(opts *envpath.Options, output *outputFormat) *cobra.Command {
+	var depth int
+
+	cmd := &cobra.Command{
+		Use:   "matrix [path]",
+		Short: "Show which directories of the current environment exist in every other environment.",
+		Long: `Walk the directories below path (default: the current directory), which must
+lie inside an environment, and mark for every environment listed by
+"changeenv list" whether the counterpart of each directory exists.`,
+		Args:          usageArgs(cobra.MaximumNArgs(1)),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(cmd, *output, outputText, outputCSV, outputJSON, outputMarkdown); err != nil {
+				return err
+			}
+			if depth < 0 {
+				return newUsageError(cmd, "--depth must not be negative")
+			}
+			path, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("determine current directory: %w", err)
+			}
+			if len(args) > 0 {
+				if !filepath.IsAbs(args[0]) {
+					path = filepath.Join(path, args[0])
+				} else {
+					path = args[0]
+				}
+			}
+
+			matrix, err := envpath.BuildMatrix(path, depth, *opts)
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			switch *output {
+			case outputCSV:
+				return printMatrixCSV(w, matrix)
+			case outputJSON:
+				return printMatrixJSON(w, matrix)
+			case outputMarkdown:
+				printMatrixMarkdown(w, matrix)
+			default:
+				printMatrix(w, matrix)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().IntVar(&depth, "depth", 0, "number of directory levels to walk below path (0 for no limit)")
+
+	return cmd
+}
+
+func presence(present bool, yes, no string) string {
+	if present {
+		return yes
+	}
+	return no
+}
+
+func printMatrix(w io.Writer, matrix *envpath.Matrix) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "PATH\t%s\n", strings.Join(matrix.Envs, "\t"))
+	for _, row := range matrix.Rows {
+		cells := make([]string, len(row.Present))
+		for i, present := range row.Present {
+			cells[i] = presence(present, "yes", "-")
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", row.Subpath, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+}
+
+func printMatrixCSV(w io.Writer, matrix *envpath.Matrix) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"path"}, matrix.Envs...)); err != nil {
+		return err
+	}
+	for _, row := range matrix.Rows {
+		record := []string{row.Subpath}
+		for _, present := range row.Present {
+			record = append(record, presence(present, "present", "missing"))
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+type matrixJSON struct {
+	Path string          `json:"path"`
+	Envs []string        `json:"envs"`
+	Rows []matrixRowJSON `json:"rows"`
+}
+
+type matrixRowJSON struct {
+	Path    string          `json:"path"`
+	Present map[string]bool `json:"present"`
+}
+
+func printMatrixJSON(w io.Writer, matrix *envpath.Matrix) error {
+	out := matrixJSON{Path: matrix.Path, Envs: matrix.Envs, Rows: make([]matrixRowJSON, len(matrix.Rows))}
+	for i, row := range matrix.Rows {
+		present := make(map[string]bool, len(row.Present))
+		for j, env := range matrix.Envs {
+			present[env] = row.Present[j]
+		}
+		out.Rows[i] = matrixRowJSON{Path: row.Subpath, Present: present}
+	}
+	return writeJSON(w, out)
+}
+
+func printMatrixMarkdown(w io.Writer, matrix *envpath.Matrix) {
+	fmt.Fprintf(w, "| path | %s |\n", strings.Join(matrix.Envs, " | "))
+	fmt.Fprintf(w, "| --- |%s\n", strings.Repeat(" :-: |", len(matrix.Envs)))
+	for _, row := range matrix.Rows {
+		cells := make([]string, len(row.Present))
+		for i, present := range row.Present {
+			cells[i] = presence(present, "✓", "✗")
+		}
+		fmt.Fprintf(w, "| `%s` | %s |\n", row.Subpath, strings.Join(cells, " | "))
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestPrintMatrixFormats(t *testing.T) {
+	matrix := &envpath.Matrix{
+		Path: "/repo/dev/app",
+		Envs: []string{"dev", "prod"},
+		Rows: []envpath.MatrixRow{
+			{Subpath: ".", Present: []bool{true, true}},
+			{Subpath: "config", Present: []bool{true, false}},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		print func(*bytes.Buffer) error
+		want  string
+	}{
+		{"text", func(buf *bytes.Buffer) error { printMatrix(buf, matrix); return nil },
+			"PATH    dev  prod\n.       yes  yes\nconfig  yes  -\n"},
+		{"csv", func(buf *bytes.Buffer) error { return printMatrixCSV(buf, matrix) },
+			"path,dev,prod\n.,present,present\nconfig,present,missing\n"},
+		{"markdown", func(buf *bytes.Buffer) error { printMatrixMarkdown(buf, matrix); return nil },
+			"| path | dev | prod |\n| --- | :-: | :-: |\n| `.` | ✓ | ✓ |\n| `config` | ✓ | ✗ |\n"},
+		{"json", func(buf *bytes.Buffer) error { return printMatrixJSON(buf, matrix) },
+			`{
+  "path": "/repo/dev/app",
+  "envs": [
+    "dev",
+    "prod"
+  ],
+  "rows": [
+    {
+      "path": ".",
+      "present": {
+        "dev": true,
+        "prod": true
+      }
+    },
+    {
+      "path": "config",
+      "present": {
+        "dev": true,
+        "prod": false
+      }
+    }
+  ]
+}
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.print(&buf); err != nil {
+				t.Fatalf("print returned error: %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"envchanger/internal/envpath"
+)
+
+// outputFormat is the value of the global --output flag.
+type outputFormat string
+
+const (
+	outputText     outputFormat = "text"
+	outputJSON     outputFormat = "json"
+	outputCSV      outputFormat = "csv"
+	outputMarkdown outputFormat = "markdown"
+)
+
+// checkOutput returns a usage error unless output is one of the formats cmd
+// supports.
+func checkOutput(cmd *cobra.Command, output outputFormat, allowed ...outputFormat) error {
+	names := make([]string, len(allowed))
+	for i, format := range allowed {
+		if output == format {
+			return nil
+		}
+		names[i] = string(format)
+	}
+	expected := strings.Join(names, " or ")
+	if len(names) > 2 {
+		expected = strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+	}
+	return newUsageError(cmd, fmt.Sprintf("invalid --output %q for %s, expected %s", output, cmd.CommandPath(), expected))
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// resultJSON is the JSON form of a resolved path.
+type resultJSON struct {
+	Source  string   `json:"source"`
+	Target  string   `json:"target"`
+	EnvFrom string   `json:"env_from"`
+	EnvTo   string   `json:"env_to"`
+	Root    string   `json:"root"`
+	Subpath string   `json:"subpath"`
+	Exists  bool     `json:"exists"`
+	Dropped []string `json:"dropped,omitempty"`
+}
+
+func newResultJSON(result *envpath.Result) resultJSON {
+	return resultJSON{
+		Source:  result.Source,
+		Target:  result.Target,
+		EnvFrom: result.EnvFrom,
+		EnvTo:   result.EnvTo,
+		Root:    result.Root,
+		Subpath: result.Subpath,
+		Exists:  result.Exists,
+		Dropped: result.Dropped,
+	}
+}
+
+// errorJSON is the JSON form of a failure. Code is one of the errorCode
+// constants; Candidates is never null.
+type errorJSON struct {
+	Code       string   `json:"code"`
+	Message    string   `json:"message"`
+	Candidates []string `json:"candidates"`
+}
+
+// Stable error codes reported in JSON mode.
+const (
+	errorCodeFailure          = "error"
+	errorCodeUsage            = "usage"
+	errorCodeAmbiguousTarget  = "ambiguous_target"
+	errorCodeNotInEnv         = "not_in_env"
+	errorCodeUnknownTarget    = "unknown_target"
+	errorCodeAmbiguousSegment = "ambiguous_segment"
+	errorCodeTargetMissing    = "target_missing"
+)
+
+// classifyError returns the JSON form of err and the exit status for it.
+// Candidates holds the environments an ambiguous target or segment could
+// name, or the suggestions for an unknown target.
+func classifyError(err error) (errorJSON, int) {
+	report := errorJSON{Code: errorCodeFailure, Message: err.Error(), Candidates: []string{}}
+	var (
+		uErr       *usageError
+		aErr       *envpath.AmbiguousTargetError
+		segmentErr *envpath.AmbiguousSegmentError
+		unknownErr *envpath.UnknownTargetError
+	)
+	switch {
+	case errors.As(err, &uErr):
+		report.Code = errorCodeUsage
+		return report, exitUsage
+	case errors.As(err, &aErr):
+		report.Code = errorCodeAmbiguousTarget
+		report.Candidates = aErr.Candidates
+		return report, exitAmbiguousTarget
+	case errors.As(err, &segmentErr):
+		report.Code = errorCodeAmbiguousSegment
+		report.Candidates = segmentErr.Candidates
+		return report, exitAmbiguousSegment
+	case errors.As(err, &unknownErr):
+		report.Code = errorCodeUnknownTarget
+		if len(unknownErr.Suggestions) > 0 {
+			report.Candidates = unknownErr.Suggestions
+		}
+		return report, exitUnknownTarget
+	case errors.Is(err, envpath.ErrTargetMissing):
+		report.Code = errorCodeTargetMissing
+		return report, exitTargetMissing
+	case errors.Is(err, envpath.ErrNotInEnv):
+		report.Code = errorCodeNotInEnv
+		return report, exitNotInEnv
+	}
+	return report, exitFailure
+}
+
+// didYouMean returns the suggestion line printed after an unknown target
+// error in text mode, or "" when there is nothing to suggest.
+func didYouMean(report errorJSON) string {
+	if report.Code != errorCodeUnknownTarget || len(report.Candidates) == 0 {
+		return ""
+	}
+	quoted := make([]string, len(report.Candidates))
+	for i, candidate := range report.Candidates {
+		quoted[i] = strconv.Quote(candidate)
+	}
+	if len(quoted) == 1 {
+		return fmt.Sprintf("did you mean %s?", quoted[0])
+	}
+	return fmt.Sprintf("did you mean one of %s?", strings.Join(quoted, ", "))
+}
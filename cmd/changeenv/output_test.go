@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCommand runs the root command with args and returns its exit status,
+// stdout and stderr.
+func runCommand(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	cmd := newRootCommand()
+	cmd.SetArgs(args)
+	cmd.SetOut(&stdout)
+	cmd.SetErr(&stderr)
+	code := run(cmd, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// outputConfig adds the preview environment, so "p" is an ambiguous target.
+const outputConfig = "[env.preview]\n"
+
+func TestRunPrintsResultAsJSON(t *testing.T) {
+	repo := setupRepo(t, outputConfig, "dev/app", "prod/app")
+	t.Chdir(filepath.Join(repo, "dev", "app"))
+
+	code, stdout, _ := runCommand(t, "--output", "json", "prod")
+	if code != 0 {
+		t.Fatalf("expected exit 0, got %d", code)
+	}
+	var got resultJSON
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", stdout, err)
+	}
+	want := resultJSON{
+		Source:  filepath.Join(repo, "dev", "app"),
+		Target:  filepath.Join(repo, "prod", "app"),
+		EnvFrom: "dev",
+		EnvTo:   "prod",
+		Root:    repo,
+		Subpath: "app",
+		Exists:  true,
+	}
+	if got.Source != want.Source || got.Target != want.Target || got.EnvFrom != want.EnvFrom ||
+		got.EnvTo != want.EnvTo || got.Root != want.Root || got.Subpath != want.Subpath || got.Exists != want.Exists {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestRunReportsErrorsAsJSON(t *testing.T) {
+	repo := setupRepo(t, outputConfig, "dev/app", "prod/app")
+	t.Chdir(filepath.Join(repo, "dev", "app"))
+
+	tests := []struct {
+		name       string
+		args       []string
+		code       int
+		errCode    string
+		candidates []string
+	}{
+		{"ambiguous target", []string{"-o", "json", "p"}, exitAmbiguousTarget, errorCodeAmbiguousTarget, []string{"prod", "preview"}},
+		{"usage error", []string{"-o", "json"}, exitUsage, errorCodeUsage, []string{}},
+		{"unknown flag", []string{"-o", "json", "--bogus"}, exitUsage, errorCodeUsage, []string{}},
+		{"unsupported format", []string{"-o", "json", "configure"}, exitUsage, errorCodeUsage, []string{}},
+		{"missing diff argument", []string{"-o", "json", "diff"}, exitUsage, errorCodeUsage, []string{}},
+		{"missing path argument", []string{"-o", "json", "path", "values.yaml"}, exitUsage, errorCodeUsage, []string{}},
+		{"extra matrix argument", []string{"-o", "json", "matrix", "a", "b"}, exitUsage, errorCodeUsage, []string{}},
+		{"not in env", []string{"-o", "json", "path", filepath.Join(repo, "notes.txt"), "prod"}, exitNotInEnv, errorCodeNotInEnv, []string{}},
+		{"unknown target", []string{"-o", "json", "prdo"}, exitUnknownTarget, errorCodeUnknownTarget, []string{"prod"}},
+		{"ambiguous segment", []string{"-o", "json", "path", filepath.Join(repo, "dev-prod.txt"), "test"}, exitAmbiguousSegment, errorCodeAmbiguousSegment, []string{"dev", "prod"}},
+		{"target missing", []string{"-o", "json", "--nearest", "test"}, exitTargetMissing, errorCodeTargetMissing, []string{}},
+	}
+	for _, tt := range tests {
+		code, stdout, stderr := runCommand(t, tt.args...)
+		if code != tt.code {
+			t.Fatalf("%s: expected exit %d, got %d", tt.name, tt.code, code)
+		}
+		if stderr != "" {
+			t.Fatalf("%s: expected nothing on stderr, got %q", tt.name, stderr)
+		}
+		var got errorJSON
+		if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+			t.Fatalf("%s: invalid JSON %q: %v", tt.name, stdout, err)
+		}
+		if got.Code != tt.errCode || got.Message == "" || strings.Join(got.Candidates, ",") != strings.Join(tt.candidates, ",") {
+			t.Fatalf("%s: unexpected error object %+v", tt.name, got)
+		}
+		if !strings.Contains(stdout, `"candidates": [`) {
+			t.Fatalf("%s: expected candidates to be an array, got %s", tt.name, stdout)
+		}
+	}
+}
+
+func TestRunReportsUsageErrorsAsText(t *testing.T) {
+	repo := setupRepo(t, outputConfig, "dev/app", "prod/app")
+	t.Chdir(filepath.Join(repo, "dev", "app"))
+
+	code, stdout, stderr := runCommand(t, "--output", "csv", "prod")
+	if code != exitUsage {
+		t.Fatalf("expected exit %d, got %d", exitUsage, code)
+	}
+	if stdout != "" || !strings.HasPrefix(stderr, `invalid --output "csv" for changeenv, expected text or json`) || !strings.Contains(stderr, "Usage:") {
+		t.Fatalf("unexpected output %q / %q", stdout, stderr)
+	}
+
+	code, _, stderr = runCommand(t, "p")
+	if code != exitAmbiguousTarget || !strings.HasPrefix(stderr, "changeenv: target environment \"p\" is ambiguous") {
+		t.Fatalf("unexpected exit %d with %q", code, stderr)
+	}
+}
+
+func TestRunSuggestsSimilarTargets(t *testing.T) {
+	repo := setupRepo(t, outputConfig, "dev/app", "prod/app")
+	t.Chdir(filepath.Join(repo, "dev", "app"))
+
+	code, stdout, stderr := runCommand(t, "prdo")
+	if code != exitUnknownTarget {
+		t.Fatalf("expected exit %d, got %d", exitUnknownTarget, code)
+	}
+	lines := strings.Split(strings.TrimSpace(stderr), "\n")
+	if stdout != "" || len(lines) != 2 || !strings.HasPrefix(lines[0], `changeenv: unknown target environment "prdo"`) || lines[1] != `changeenv: did you mean "prod"?` {
+		t.Fatalf("unexpected output %q / %q", stdout, stderr)
+	}
+}
+
+func TestRunSeparatesDifferencesFromFailures(t *testing.T) {
+	repo := setupRepo(t, outputConfig, "dev/app", "prod/app")
+	t.Chdir(filepath.Join(repo, "dev", "app"))
+	mustWrite(t, filepath.Join(repo, "dev", "app", "debug.yaml"), "")
+
+	if code, _, _ := runCommand(t, "drift"); code != exitDifferences {
+		t.Fatalf("expected exit %d for drift, got %d", exitDifferences, code)
+	}
+
+	mustWrite(t, filepath.Join(repo, ".cenv.toml"), "bogus = 1\n")
+	if code, _, _ := runCommand(t, "drift"); code != exitFailure {
+		t.Fatalf("expected exit %d for an invalid config, got %d", exitFailure, code)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"envchanger/internal/envpath"
+)
+
+func newPathCommand(opts *envpath.Options, output *outputFormat) *cobra.Command {
+	return &cobra.Command{
+		Use:   "path <file> <env>",
+		Short: "Print the counterpart of a file or directory in another environment.",
+		Long: `Map file, which may be a file or directory and need not exist, into env and
+print the result. Environment names in the file name are rewritten as well, so
+values-dev.yaml becomes values-prod.yaml. Name tokens must be delimited by the
+characters in the token_boundaries setting ("-_." by default).
+
+Relative paths are resolved against the current directory. A warning is
+printed to stderr when the counterpart does not exist.`,
+		Args:          usageArgs(cobra.ExactArgs(2)),
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(cmd, *output, outputText, outputJSON); err != nil {
+				return err
+			}
+			targetEnv := strings.TrimSpace(args[1])
+			if targetEnv == "" {
+				return newUsageError(cmd, "target environment must not be empty")
+			}
+			path := args[0]
+			if !filepath.IsAbs(path) {
+				cwd, err := os.Getwd()
+				if err != nil {
+					return fmt.Errorf("determine current directory: %w", err)
+				}
+				path = filepath.Join(cwd, path)
+			}
+
+			result, err := envpath.ResolveFile(path, targetEnv, *opts)
+			if err != nil {
+				return err
+			}
+			for _, warning := range result.Warnings {
+				fmt.Fprintf(cmd.ErrOrStderr(), "changeenv: warning: %s\n", warning)
+			}
+			if !result.Exists {
+				fmt.Fprintf(cmd.ErrOrStderr(), "changeenv: warning: %s does not exist\n", result.Target)
+			}
+			if *output == outputJSON {
+				return writeJSON(cmd.OutOrStdout(), newResultJSON(result))
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), result.Target)
+			return nil
+		},
+	}
+}
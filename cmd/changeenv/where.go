@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"envchanger/internal/envpath"
+)
+
+func newWhereCommand(opts *envpath.Options, output *outputFormat) *cobra.Command {
+	return &cobra.Command{
+		Use:           "where",
+		Short:         "Show the root, environment and layout dimensions of the current directory.",
+		Args:          validateNoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkOutput(cmd, *output, outputText, outputJSON); err != nil {
+				return err
+			}
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("determine current directory: %w", err)
+			}
+			loc, err := envpath.Locate(cwd, *opts)
+			if err != nil {
+				return err
+			}
+			if *output == outputJSON {
+				return writeJSON(cmd.OutOrStdout(), newLocationJSON(loc))
+			}
+			homeDir, _ := os.UserHomeDir()
+			printLocation(cmd.OutOrStdout(), loc, homeDir)
+			return nil
+		},
+	}
+}
+
+func printLocation(w io.Writer, loc *envpath.Location, homeDir string) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "path:\t%s\n", loc.Path)
+	fmt.Fprintf(tw, "root:\t%s\n", valueOrNone(loc.Root))
+	if loc.Env != "" {
+		fmt.Fprintf(tw, "env:\t%s\n", loc.Env)
+		if loc.EnvDir != loc.Env {
+			fmt.Fprintf(tw, "env dir:\t%s\n", loc.EnvDir)
+		}
+		fmt.Fprintf(tw, "subpath:\t%s\n", valueOrNone(loc.Subpath))
+		if loc.Marker != nil {
+			fmt.Fprintf(tw, "marker:\t%s\n", displayPath(loc.Marker.Path, homeDir))
+			if loc.Marker.Description != "" {
+				fmt.Fprintf(tw, "description:\t%s\n", loc.Marker.Description)
+			}
+			for _, key := range sortedKeys(loc.Marker.Meta) {
+				fmt.Fprintf(tw, "meta %s:\t%s\n", key, loc.Marker.Meta[key])
+			}
+		}
+		if len(loc.Candidates) > 1 {
+			dirs := make([]string, len(loc.Candidates))
+			for i, candidate := range loc.Candidates {
+				dirs[i] = candidate.Dir
+			}
+			fmt.Fprintf(tw, "candidates:\t%s\n", strings.Join(dirs, ", "))
+		}
+	} else {
+		fmt.Fprintf(tw, "env:\t%s\n", "(none)")
+	}
+	if loc.Layout == nil {
+		fmt.Fprintf(tw, "layout:\t%s\n", "(none)")
+		tw.Flush()
+		return
+	}
+	fmt.Fprintf(tw, "layout:\t%s  (%s)\n", loc.Layout.Template, formatOrigin(loc.Layout.Origin, homeDir))
+	tw.Flush()
+
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, dim := range loc.Dimensions {
+		fmt.Fprintf(tw, "  %s\t%s\n", dim.Name, dim.Value)
+	}
+	tw.Flush()
+}
+
+// locationJSON is the JSON form of a located path.
+type locationJSON struct {
+	Path       string            `json:"path"`
+	Root       string            `json:"root"`
+	Env        string            `json:"env"`
+	EnvDir     string            `json:"env_dir"`
+	Subpath    string            `json:"subpath"`
+	Candidates []string          `json:"candidates"`
+	Layout     string            `json:"layout,omitempty"`
+	Dimensions map[string]string `json:"dimensions,omitempty"`
+	Marker     *markerJSON       `json:"marker,omitempty"`
+}
+
+type markerJSON struct {
+	Path        string            `json:"path"`
+	Env         string            `json:"env"`
+	Description string            `json:"description,omitempty"`
+	Meta        map[string]string `json:"meta,omitempty"`
+}
+
+func newLocationJSON(loc *envpath.Location) locationJSON {
+	out := locationJSON{
+		Path:       loc.Path,
+		Root:       loc.Root,
+		Env:        loc.Env,
+		EnvDir:     loc.EnvDir,
+		Subpath:    loc.Subpath,
+		Candidates: make([]string, len(loc.Candidates)),
+	}
+	for i, candidate := range loc.Candidates {
+		out.Candidates[i] = candidate.Dir
+	}
+	if loc.Layout != nil {
+		out.Layout = loc.Layout.Template
+		out.Dimensions = make(map[string]string, len(loc.Dimensions))
+		for _, dim := range loc.Dimensions {
+			out.Dimensions[dim.Name] = dim.Value
+		}
+	}
+	if loc.Marker != nil {
+		out.Marker = &markerJSON{
+			Path:        loc.Marker.Path,
+			Env:         loc.Marker.Env,
+			Description: loc.Marker.Description,
+			Meta:        loc.Marker.Meta,
+		}
+	}
+	return out
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
@@ -0,0 +1,104 @@
+package envpath
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// CaseMode controls how environment names and directories are compared while
+// resolving a path.
+type CaseMode string
+
+const (
+	// CaseInsensitive compares names and directories case-insensitively and
+	// writes the target directory with the casing found on disk. It is the
+	// default.
+	CaseInsensitive CaseMode = "insensitive"
+	// CaseSensitive compares names and directories exactly and writes the
+	// target exactly as configured or typed.
+	CaseSensitive CaseMode = "sensitive"
+	// CaseSmart behaves like CaseInsensitive, except that a target containing
+	// upper-case letters must match exactly.
+	CaseSmart CaseMode = "smart"
+)
+
+var caseModes = []CaseMode{CaseInsensitive, CaseSensitive, CaseSmart}
+
+// ParseCaseMode validates a case mode name.
+func ParseCaseMode(name string) (CaseMode, error) {
+	for _, mode := range caseModes {
+		if strings.EqualFold(name, string(mode)) {
+			return mode, nil
+		}
+	}
+	names := make([]string, len(caseModes))
+	for i, mode := range caseModes {
+		names[i] = string(mode)
+	}
+	return "", fmt.Errorf("unknown case mode %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// caseMode returns the configured case mode.
+func (c *Config) caseMode() CaseMode {
+	if c.Case.Value != "" {
+		return c.Case.Value
+	}
+	return CaseInsensitive
+}
+
+// sameSegment compares a path segment with an environment directory, or two
+// environment names, according to the case mode.
+func (c *Config) sameSegment(a, b string) bool {
+	if c.caseMode() == CaseSensitive {
+		return a == b
+	}
+	return strings.EqualFold(a, b)
+}
+
+// foldTarget reports whether target is matched case-insensitively against
+// environment names and aliases.
+func (c *Config) foldTarget(target string) bool {
+	switch c.caseMode() {
+	case CaseSensitive:
+		return false
+	case CaseSmart:
+		return strings.ToLower(target) == target
+	default:
+		return true
+	}
+}
+
+// diskCase returns segments with each one replaced by the name of the
+// directory on disk that matches it case-insensitively, starting in parent.
+// Exact matches are kept; a segment is left alone when it is missing or
+// several directories differ from it only in case.
+func diskCase(parent string, segments []string) []string {
+	cased := slices.Clone(segments)
+	dir := parent
+	for i, segment := range cased {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			break
+		}
+		var matches []string
+		for _, entry := range entries {
+			name := entry.Name()
+			if name == segment {
+				matches = []string{name}
+				break
+			}
+			if strings.EqualFold(name, segment) && isDir(filepath.Join(dir, name)) {
+				matches = append(matches, name)
+			}
+		}
+		if len(matches) != 1 {
+			break
+		}
+		cased[i] = matches[0]
+		dir = filepath.Join(dir, matches[0])
+	}
+	return cased
+}
@@ -0,0 +1,128 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestResolvePreservesOnDiskCasing(t *testing.T) {
+	repo := setupRepo(t, "")
+	devPath := filepath.Join(repo, "dev", "app")
+	mustMkdirAll(t, devPath)
+	mustMkdirAll(t, filepath.Join(repo, "Staging", "app"))
+	mustMkdirAll(t, filepath.Join(repo, "Prod"))
+
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{"STAGING", filepath.Join(repo, "Staging", "app")},
+		{"prod", filepath.Join(repo, "Prod", "app")},
+		{"test", filepath.Join(repo, "test", "app")},
+	}
+	for _, tt := range tests {
+		result, err := envpath.Resolve(devPath, tt.target, envpath.Options{})
+		if err != nil {
+			t.Fatalf("Resolve(%q) returned error: %v", tt.target, err)
+		}
+		if result.Target != tt.expected {
+			t.Fatalf("Resolve(%q): expected %q, got %q", tt.target, tt.expected, result.Target)
+		}
+	}
+}
+
+func TestResolveKeepsExactCasingWhenSeveralMatch(t *testing.T) {
+	repo := setupRepo(t, "")
+	devPath := filepath.Join(repo, "dev")
+	mustMkdirAll(t, devPath)
+	mustMkdirAll(t, filepath.Join(repo, "QA"))
+	mustMkdirAll(t, filepath.Join(repo, "Qa"))
+
+	result, err := envpath.Resolve(devPath, "qa", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "qa"); result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+}
+
+func TestResolveCaseSensitive(t *testing.T) {
+	repo := setupRepo(t, "case = \"sensitive\"\n")
+	mustMkdirAll(t, filepath.Join(repo, "Prod"))
+
+	if _, err := envpath.Resolve(filepath.Join(repo, "DEV", "app"), "prod", envpath.Options{}); err == nil {
+		t.Fatalf("expected DEV not to be recognised as dev")
+	}
+
+	result, err := envpath.Resolve(filepath.Join(repo, "dev", "app"), "PROD", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "PROD", "app"); result.Target != expected {
+		t.Fatalf("expected the target written as typed, got %q", result.Target)
+	}
+}
+
+func TestResolveCaseSensitiveMatchesConfiguredNamesExactly(t *testing.T) {
+	repo := setupRepo(t, `case = "sensitive"
+
+[env.prod]
+dirs = ["production"]
+
+[[rewrite]]
+from = "DEV"
+match = '^app'
+replace = 'never'
+`, "dev/app/values-DEV.yaml", "production/")
+	devPath := filepath.Join(repo, "dev", "app")
+
+	result, err := envpath.Resolve(devPath, "PROD", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "PROD", "app"); result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+
+	result, err = envpath.ResolveFile(filepath.Join(devPath, "values-DEV.yaml"), "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("ResolveFile returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "production", "app", "values-DEV.yaml"); result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+}
+
+func TestResolveCaseSmart(t *testing.T) {
+	repo := setupRepo(t, "case = \"smart\"\n")
+	devPath := filepath.Join(repo, "DEV", "app")
+	mustMkdirAll(t, devPath)
+	mustMkdirAll(t, filepath.Join(repo, "Prod"))
+
+	result, err := envpath.Resolve(devPath, "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "Prod", "app"); result.Target != expected || result.EnvFrom != "dev" {
+		t.Fatalf("expected %q from dev, got %q from %q", expected, result.Target, result.EnvFrom)
+	}
+
+	result, err = envpath.Resolve(devPath, "PROD", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "PROD", "app"); result.Target != expected || result.EnvTo != "PROD" {
+		t.Fatalf("expected an upper-case target to match exactly, got %q (%s)", result.Target, result.EnvTo)
+	}
+}
+
+func TestLoadConfigRejectsUnknownCaseMode(t *testing.T) {
+	repo := setupRepo(t, "case = \"upper\"\n")
+	if _, err := envpath.LoadConfig(repo, ""); err == nil || !strings.Contains(err.Error(), `unknown case mode "upper"`) {
+		t.Fatalf("expected unknown case mode error, got %v", err)
+	}
+}
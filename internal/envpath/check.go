@@ -0,0 +1,103 @@
+package envpath
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Check reports problems in the merged configuration: files that could not be
+// read, environments declared more than once, names that shadow each other
+// case-insensitively, names or aliases containing path separators, malformed
+// directories, invalid patterns and compound patterns, directories claimed by
+// several environments, and aliases that collide with another environment or
+// alias.
+func (c *Config) Check() []Issue {
+	issues := append([]Issue(nil), c.Issues...)
+
+	for i, decl := range c.declarations {
+		if hasPathSeparator(decl.name) {
+			issues = append(issues, Issue{Origin: decl.origin, Message: fmt.Sprintf("environment name %q contains a path separator", decl.name)})
+		}
+		for _, prev := range c.declarations[:i] {
+			if !strings.EqualFold(prev.name, decl.name) {
+				continue
+			}
+			if prev.name != decl.name {
+				issues = append(issues, Issue{Origin: decl.origin, Message: fmt.Sprintf("environment %q shadows %q defined at %s", decl.name, prev.name, prev.origin)})
+			} else if !decl.reopen {
+				issues = append(issues, Issue{Origin: decl.origin, Message: fmt.Sprintf("duplicate environment %q, already defined at %s", decl.name, prev.origin)})
+			}
+			break
+		}
+	}
+
+	for _, pattern := range c.Patterns {
+		if err := pattern.Err(); err != nil {
+			issues = append(issues, Issue{Origin: pattern.Origin, Message: fmt.Sprintf("invalid pattern %q: %v", pattern.Raw, err)})
+		}
+	}
+
+	for _, rule := range c.Ignore {
+		if err := rule.Err(); err != nil {
+			issues = append(issues, Issue{Origin: rule.Origin, Message: fmt.Sprintf("invalid ignore rule %q: %v", rule.Raw, err)})
+		}
+	}
+
+	if c.Compound != nil && c.Compound.Err() != nil {
+		issues = append(issues, Issue{Origin: c.Compound.Origin, Message: fmt.Sprintf("invalid compound pattern %q: %v", c.Compound.Raw, c.Compound.Err())})
+	}
+
+	dirOwners := make(map[string]string)
+	for _, env := range c.Envs {
+		for _, dir := range env.Dirs.Value {
+			if !validEnvDir(dir) {
+				issues = append(issues, Issue{Origin: env.Dirs.Origin, Message: fmt.Sprintf("directory %q of %q must be a relative path of plain segments separated by /", dir, env.Name)})
+			}
+		}
+		for _, dir := range env.DirNames() {
+			key := strings.ToLower(dir)
+			if owner, ok := dirOwners[key]; ok && owner != env.Name {
+				issues = append(issues, Issue{Origin: env.Dirs.Origin, Message: fmt.Sprintf("directory %q of %q is already used by %q", dir, env.Name, owner)})
+				continue
+			}
+			dirOwners[key] = env.Name
+		}
+	}
+
+	aliasOwners := make(map[string]string)
+	for _, env := range c.Envs {
+		for _, alias := range env.Aliases {
+			if hasPathSeparator(alias.Name) {
+				issues = append(issues, Issue{Origin: alias.Origin, Message: fmt.Sprintf("alias %q of %q contains a path separator", alias.Name, env.Name)})
+			}
+			if other, ok := c.Lookup(alias.Name); ok {
+				issues = append(issues, Issue{Origin: alias.Origin, Message: fmt.Sprintf("alias %q of %q is hidden by environment %q", alias.Name, env.Name, other.Name)})
+				continue
+			}
+			key := strings.ToLower(alias.Name)
+			if owner, ok := aliasOwners[key]; ok && owner != env.Name {
+				issues = append(issues, Issue{Origin: alias.Origin, Message: fmt.Sprintf("alias %q of %q is already an alias of %q", alias.Name, env.Name, owner)})
+				continue
+			}
+			aliasOwners[key] = env.Name
+		}
+	}
+
+	return issues
+}
+
+// validEnvDir reports whether dir is one or more plain segments joined by "/",
+// such as "prod" or "prod/eu".
+func validEnvDir(dir string) bool {
+	for _, segment := range strings.Split(dir, "/") {
+		if segment == "" || segment == "." || segment == ".." || hasPathSeparator(segment) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasPathSeparator(name string) bool {
+	return strings.ContainsRune(name, '/') || strings.ContainsRune(name, filepath.Separator)
+}
@@ -0,0 +1,138 @@
+package envpath
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckReportsDuplicatesShadowsAndSeparators(t *testing.T) {
+	home := isolateConfig(t)
+	cenvrc := filepath.Join(home, ".cenvrc")
+	writeFile(t, cenvrc, "staging\nprod\nStaging\nteam/app\n")
+	userConfig := filepath.Join(home, ".config", "changeenv", "config.toml")
+	writeFile(t, userConfig, "[env.staging]\naliases = [\"stg/eu\"]\n")
+
+	cfg, err := LoadConfig(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	var got []string
+	for _, issue := range cfg.Check() {
+		got = append(got, issue.String())
+	}
+	expected := []string{
+		cenvrc + `:2: duplicate environment "prod", already defined at built-in`,
+		cenvrc + `:3: environment "Staging" shadows "staging" defined at ` + cenvrc + ":1",
+		cenvrc + `:4: environment name "team/app" contains a path separator`,
+		userConfig + `:2: alias "stg/eu" of "staging" contains a path separator`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestCheckAllowsReopeningEnvironmentTables(t *testing.T) {
+	home := isolateConfig(t)
+	writeFile(t, filepath.Join(home, ".config", "changeenv", "config.toml"), "[env.prod]\ndescription = \"Production\"\n")
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ".cenv.toml"), "[env.prod]\naliases = [\"live\"]\n")
+
+	cfg, err := LoadConfig(repo, "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if issues := cfg.Check(); len(issues) != 0 {
+		t.Fatalf("expected no issues, got %v", issues)
+	}
+}
+
+func TestCheckReportsUnreadableFiles(t *testing.T) {
+	home := isolateConfig(t)
+	// A directory in place of the file cannot be read as an environment list.
+	if err := os.MkdirAll(filepath.Join(home, ".cenvrc"), 0o755); err != nil {
+		t.Fatalf("create directory: %v", err)
+	}
+
+	cfg, err := LoadConfig(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	issues := cfg.Check()
+	if len(issues) != 1 || !strings.Contains(issues[0].Message, "cannot read file") {
+		t.Fatalf("expected unreadable file issue, got %v", issues)
+	}
+}
+
+func TestLoadConfigForCheckRecordsBrokenTOMLFiles(t *testing.T) {
+	home := isolateConfig(t)
+	// A directory in place of the user config file cannot be read.
+	if err := os.MkdirAll(filepath.Join(home, ".config", "changeenv", "config.toml"), 0o755); err != nil {
+		t.Fatalf("create directory: %v", err)
+	}
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ".cenv.toml"), "env_marker = \"ok\"\nbogus = 1\n")
+
+	if _, err := LoadConfig(repo, ""); err == nil {
+		t.Fatalf("expected LoadConfig to fail")
+	}
+	cfg, err := LoadConfigForCheck(repo, "")
+	if err != nil {
+		t.Fatalf("LoadConfigForCheck returned error: %v", err)
+	}
+	issues := cfg.Check()
+	if len(issues) != 2 {
+		t.Fatalf("expected two issues, got %v", issues)
+	}
+	if !strings.Contains(issues[0].Message, "cannot read file") {
+		t.Fatalf("expected unreadable file issue, got %v", issues[0])
+	}
+	if got := issues[1].String(); got != filepath.Join(repo, ".cenv.toml")+`:2: unknown key "bogus"` {
+		t.Fatalf("unexpected malformed file issue %q", got)
+	}
+}
+
+func TestCheckReportsAliasCollisions(t *testing.T) {
+	home := isolateConfig(t)
+	userConfig := filepath.Join(home, ".config", "changeenv", "config.toml")
+	writeFile(t, userConfig, `[env.prod]
+aliases = ["p", "test"]
+[env.preview]
+aliases = ["p"]
+`)
+
+	cfg, err := LoadConfig(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	var got []string
+	for _, issue := range cfg.Check() {
+		got = append(got, issue.String())
+	}
+	expected := []string{
+		userConfig + `:2: alias "test" of "prod" is hidden by environment "test"`,
+		userConfig + `:4: alias "p" of "preview" is already an alias of "prod"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("unexpected issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestCheckReportsSharedDirectories(t *testing.T) {
+	home := isolateConfig(t)
+	userConfig := filepath.Join(home, ".config", "changeenv", "config.toml")
+	writeFile(t, userConfig, "[env.staging]\ndirs = [\"Test\"]\n")
+
+	cfg, err := LoadConfig(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	issues := cfg.Check()
+	want := userConfig + `:2: directory "Test" of "staging" is already used by "test"`
+	if len(issues) != 1 || issues[0].String() != want {
+		t.Fatalf("expected %q, got %v", want, issues)
+	}
+}
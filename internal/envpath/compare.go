@@ -0,0 +1,148 @@
+package envpath
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// FilePair is a file and its counterpart in another environment.
+type FilePair struct {
+	// Path is the slash-separated location of the file relative to the
+	// compared path, or its base name when a single file was compared.
+	Path string
+	// From is the file in the current environment. It is empty when only the
+	// target environment has the file.
+	From string
+	// To is the counterpart in the target environment. It is empty when only
+	// the current environment has the file.
+	To string
+}
+
+// CounterpartFiles resolves path in targetEnv and pairs every file below it
+// with its counterpart there, ordered by Path. Files are mapped like Resolve
+// maps their directories, so rewrite rules and marker roots apply; files only
+// present in the target tree are paired by mapping them back. When path is a
+// file, its directory is resolved and the single file is paired. Hidden files
+// are skipped.
+func CounterpartFiles(path, targetEnv string, opts Options) (*Result, []FilePair, error) {
+	if path == "" {
+		return nil, nil, errors.New("path must not be empty")
+	}
+	cleanPath := filepath.Clean(path)
+	info, err := os.Stat(cleanPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := opts.config(cleanPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !info.IsDir() {
+		result, err := resolve(filepath.Dir(cleanPath), targetEnv, opts, cfg, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		base := filepath.Base(cleanPath)
+		pair := FilePair{Path: base, From: cleanPath}
+		if to := filepath.Join(result.Target, base); isFile(to) {
+			pair.To = to
+		}
+		result.Source, result.Target = cleanPath, filepath.Join(result.Target, base)
+		result.Exists = pair.To != ""
+		return result, []FilePair{pair}, nil
+	}
+
+	result, err := resolve(cleanPath, targetEnv, opts, cfg, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// counterpartDir caches the counterpart of a directory in an environment.
+	type dirEnv struct{ dir, env string }
+	resolved := make(map[dirEnv]string)
+	counterpartDir := func(dir, env string) string {
+		key := dirEnv{dir, env}
+		if target, ok := resolved[key]; ok {
+			return target
+		}
+		target := ""
+		if r, err := resolve(dir, env, opts, cfg, nil); err == nil {
+			target = r.Target
+		}
+		resolved[key] = target
+		return target
+	}
+
+	pairs := make(map[string]*FilePair)
+	err = walkFiles(cleanPath, func(file string) {
+		rel, err := filepath.Rel(cleanPath, file)
+		if err != nil {
+			return
+		}
+		pair := &FilePair{Path: filepath.ToSlash(rel), From: file}
+		if dir := counterpartDir(filepath.Dir(file), result.EnvTo); dir != "" {
+			if to := filepath.Join(dir, filepath.Base(file)); isFile(to) {
+				pair.To = to
+			}
+		}
+		pairs[pair.Path] = pair
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if result.Exists {
+		paired := make(map[string]bool, len(pairs))
+		for _, pair := range pairs {
+			if pair.To != "" {
+				paired[pair.To] = true
+			}
+		}
+		err = walkFiles(result.Target, func(file string) {
+			if paired[file] {
+				return
+			}
+			// Map the file back into the current environment to name it
+			// like the files found there.
+			from := ""
+			if dir := counterpartDir(filepath.Dir(file), result.EnvFrom); dir != "" {
+				from = filepath.Join(dir, filepath.Base(file))
+			}
+			rel, err := filepath.Rel(cleanPath, from)
+			if from == "" || err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				rel, err = filepath.Rel(result.Target, file)
+				if err != nil {
+					return
+				}
+			}
+			key := filepath.ToSlash(rel)
+			if pair, ok := pairs[key]; ok && pair.To == "" {
+				pair.To = file
+				return
+			}
+			pairs[key] = &FilePair{Path: key, To: file}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	list := make([]FilePair, 0, len(pairs))
+	for _, pair := range pairs {
+		list = append(list, *pair)
+	}
+	slices.SortFunc(list, func(a, b FilePair) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return result, list, nil
+}
+
+// isFile reports whether path exists and is not a directory.
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
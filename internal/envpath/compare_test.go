@@ -0,0 +1,82 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestCounterpartFilesPairsBothTrees(t *testing.T) {
+	repo := setupRepo(t, "", driftFiles...)
+
+	result, pairs, err := envpath.CounterpartFiles(filepath.Join(repo, "dev"), "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("CounterpartFiles returned error: %v", err)
+	}
+	if result.Target != filepath.Join(repo, "prod") {
+		t.Fatalf("unexpected target %q", result.Target)
+	}
+	expected := []envpath.FilePair{
+		{Path: "alerts.yaml", To: filepath.Join(repo, "prod", "alerts.yaml")},
+		{Path: "app/debug.yaml", From: filepath.Join(repo, "dev", "app", "debug.yaml")},
+		{Path: "app/values.yaml", From: filepath.Join(repo, "dev", "app", "values.yaml"), To: filepath.Join(repo, "prod", "app", "values.yaml")},
+	}
+	if len(pairs) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, pairs)
+	}
+	for i, want := range expected {
+		if pairs[i] != want {
+			t.Fatalf("pair %d: expected %+v, got %+v", i, want, pairs[i])
+		}
+	}
+}
+
+func TestCounterpartFilesSingleFile(t *testing.T) {
+	repo := setupRepo(t, "", driftFiles...)
+	file := filepath.Join(repo, "dev", "app", "values.yaml")
+
+	result, pairs, err := envpath.CounterpartFiles(file, "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("CounterpartFiles returned error: %v", err)
+	}
+	want := envpath.FilePair{Path: "values.yaml", From: file, To: filepath.Join(repo, "prod", "app", "values.yaml")}
+	if len(pairs) != 1 || pairs[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, pairs)
+	}
+	if result.Target != want.To || !result.Exists {
+		t.Fatalf("unexpected result %+v", result)
+	}
+}
+
+func TestCounterpartFilesFollowsRewriteRules(t *testing.T) {
+	repo := setupRepo(t, `[[rewrite]]
+from = "dev"
+to = "prod"
+match = '^app(/|$)'
+replace = 'application${1}'
+
+[[rewrite]]
+from = "prod"
+to = "dev"
+match = '^application(/|$)'
+replace = 'app${1}'
+`, "dev/app/values.yaml", "prod/application/values.yaml", "prod/application/alerts.yaml")
+
+	_, pairs, err := envpath.CounterpartFiles(filepath.Join(repo, "dev"), "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("CounterpartFiles returned error: %v", err)
+	}
+	expected := []envpath.FilePair{
+		{Path: "app/alerts.yaml", To: filepath.Join(repo, "prod", "application", "alerts.yaml")},
+		{Path: "app/values.yaml", From: filepath.Join(repo, "dev", "app", "values.yaml"), To: filepath.Join(repo, "prod", "application", "values.yaml")},
+	}
+	if len(pairs) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, pairs)
+	}
+	for i, want := range expected {
+		if pairs[i] != want {
+			t.Fatalf("pair %d: expected %+v, got %+v", i, want, pairs[i])
+		}
+	}
+}
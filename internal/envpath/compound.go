@@ -0,0 +1,148 @@
+package envpath
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// Compound decomposes environment names such as "test-eu-central-1" into
+// named components using a regular expression with named capture groups, for
+// example ^(?P<stage>dev|test|prod)-(?P<region>[a-z]+-[a-z]+-\d+)$.
+type Compound struct {
+	Raw    string
+	Origin Origin
+
+	re         *regexp.Regexp
+	components []component
+	err        error
+}
+
+type component struct {
+	name string
+	re   *regexp.Regexp
+}
+
+func newCompound(raw string, origin Origin) *Compound {
+	c := &Compound{Raw: raw, Origin: origin}
+	c.re, c.err = regexp.Compile("(?i)" + raw)
+	if c.err != nil {
+		return c
+	}
+	parsed, err := syntax.Parse(raw, syntax.Perl)
+	if err != nil {
+		c.err = err
+		return c
+	}
+	var walk func(*syntax.Regexp)
+	walk = func(re *syntax.Regexp) {
+		if re.Op == syntax.OpCapture && re.Name != "" {
+			sub := regexp.MustCompile("(?i)^(?:" + re.Sub[0].String() + ")$")
+			c.components = append(c.components, component{name: re.Name, re: sub})
+		}
+		for _, sub := range re.Sub {
+			walk(sub)
+		}
+	}
+	walk(parsed)
+	if len(c.components) == 0 {
+		c.err = errors.New("pattern has no named capture groups")
+	}
+	return c
+}
+
+// Err returns the compilation error of an invalid compound pattern.
+func (c *Compound) Err() error {
+	return c.err
+}
+
+// Components returns the named components of name, or nil if name does not
+// match the pattern.
+func (c *Compound) Components(name string) map[string]string {
+	if c == nil || c.err != nil {
+		return nil
+	}
+	match := c.re.FindStringSubmatch(name)
+	if match == nil || len(match[0]) != len(name) {
+		return nil
+	}
+	components := make(map[string]string)
+	for _, comp := range c.components {
+		components[comp.name] = match[c.re.SubexpIndex(comp.name)]
+	}
+	return components
+}
+
+// Component returns the name of the component that partial matches, or false
+// if partial is a complete compound name itself or matches no component.
+func (c *Compound) Component(partial string) (string, bool) {
+	if c == nil || c.err != nil || c.Components(partial) != nil {
+		return "", false
+	}
+	for _, comp := range c.components {
+		if comp.re.MatchString(partial) {
+			return comp.name, true
+		}
+	}
+	return "", false
+}
+
+// Replace substitutes the component of current that partial matches. It
+// returns the composed name and the component that was replaced, or false if
+// current is not a compound name or partial is a complete name itself.
+func (c *Compound) Replace(current, partial string) (string, string, bool) {
+	if c == nil || c.err != nil || c.Components(partial) != nil {
+		return "", "", false
+	}
+	loc := c.re.FindStringSubmatchIndex(current)
+	if loc == nil || loc[0] != 0 || loc[1] != len(current) {
+		return "", "", false
+	}
+	for _, comp := range c.components {
+		if !comp.re.MatchString(partial) {
+			continue
+		}
+		idx := c.re.SubexpIndex(comp.name)
+		start, end := loc[2*idx], loc[2*idx+1]
+		if start < 0 {
+			continue
+		}
+		return current[:start] + partial + current[end:], comp.name, true
+	}
+	return "", "", false
+}
+
+// composeTarget applies the compound configuration to a switch from the
+// environment directory current to target, both located in parent. The
+// composed name is preferred when its directory exists; otherwise the literal
+// target is used if it exists. If neither exists, an error names both.
+func (c *Config) composeTarget(parent, current, target, targetDir string, tr *Trace) (string, error) {
+	composed, name, ok := c.Compound.Replace(current, target)
+	if !ok {
+		return targetDir, nil
+	}
+	if dir := c.compoundDir(composed); tr.exists(filepath.Join(parent, dir)) {
+		tr.step("compound: replaced %s of %q with %q, giving %q", name, current, target, dir)
+		return dir, nil
+	}
+	if tr.exists(filepath.Join(parent, targetDir)) {
+		tr.step("compound: %q does not exist, using %q", composed, targetDir)
+		return targetDir, nil
+	}
+	return "", fmt.Errorf("environment %q (%s of %q replaced by %q): %w", composed, name, current, target, &TargetMissingError{
+		Path:         filepath.Join(parent, c.compoundDir(composed)),
+		Alternatives: []string{filepath.Join(parent, targetDir)},
+	})
+}
+
+// compoundDir returns the directory for a composed environment name, honouring
+// configured directory names.
+func (c *Config) compoundDir(name string) string {
+	if env, ok := c.Lookup(name); ok {
+		return env.Dir()
+	}
+	return strings.TrimSpace(name)
+}
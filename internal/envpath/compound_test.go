@@ -0,0 +1,117 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func setupCompoundConfig(t *testing.T) string {
+	t.Helper()
+	home := envpath.IsolateConfig(t)
+	envpath.WriteFile(t, filepath.Join(home, ".config", "changeenv", "config.toml"), `patterns = ["^(dev|test|prod)-"]
+
+[compound]
+pattern = '^(?P<stage>dev|test|prod)-(?P<region>[a-z]+-[a-z]+-\d+)$'
+`)
+	return home
+}
+
+func TestSwitchReplacesCompoundComponent(t *testing.T) {
+	setupCompoundConfig(t)
+
+	root := t.TempDir()
+	from := filepath.Join(root, "test-eu-central-1", "app")
+	mustMkdirAll(t, from)
+	mustMkdirAll(t, filepath.Join(root, "prod-eu-central-1"))
+	mustMkdirAll(t, filepath.Join(root, "test-us-east-1"))
+
+	cases := map[string]string{
+		"prod":      filepath.Join(root, "prod-eu-central-1", "app"),
+		"us-east-1": filepath.Join(root, "test-us-east-1", "app"),
+	}
+	for target, expected := range cases {
+		result, err := envpath.Resolve(from, target, envpath.Options{})
+		if err != nil {
+			t.Fatalf("Resolve(%q) returned error: %v", target, err)
+		}
+		if result.Target != expected {
+			t.Fatalf("Resolve(%q): expected %q, got %q", target, expected, result.Target)
+		}
+	}
+}
+
+func TestSwitchCompoundComponentIsNotExpandedAsPrefix(t *testing.T) {
+	for _, envs := range []string{
+		"test-eu-central-1 prod-us-east-1",
+		"test-eu-central-1 prod-us-east-1 prod-eu-central-1",
+	} {
+		home := setupCompoundConfig(t)
+		envpath.WriteFile(t, filepath.Join(home, ".cenvrc"), "!*\n"+strings.ReplaceAll(envs, " ", "\n")+"\n")
+
+		root := t.TempDir()
+		from := filepath.Join(root, "test-eu-central-1", "app")
+		mustMkdirAll(t, from)
+		mustMkdirAll(t, filepath.Join(root, "prod-us-east-1", "app"))
+		mustMkdirAll(t, filepath.Join(root, "prod-eu-central-1", "app"))
+
+		result, err := envpath.Resolve(from, "prod", envpath.Options{})
+		if err != nil {
+			t.Fatalf("%s: Resolve returned error: %v", envs, err)
+		}
+		if expected := filepath.Join(root, "prod-eu-central-1", "app"); result.Target != expected {
+			t.Fatalf("%s: expected %q, got %q", envs, expected, result.Target)
+		}
+	}
+}
+
+func TestSwitchCompoundFallsBackToLiteralTarget(t *testing.T) {
+	setupCompoundConfig(t)
+
+	root := t.TempDir()
+	from := filepath.Join(root, "test-eu-central-1", "app")
+	mustMkdirAll(t, from)
+	mustMkdirAll(t, filepath.Join(root, "prod"))
+
+	target, err := envpath.Switch(from, "prod")
+	if err != nil {
+		t.Fatalf("Switch returned error: %v", err)
+	}
+	if expected := filepath.Join(root, "prod", "app"); target != expected {
+		t.Fatalf("expected %q, got %q", expected, target)
+	}
+}
+
+func TestSwitchCompoundReportsMissingCombination(t *testing.T) {
+	setupCompoundConfig(t)
+
+	root := t.TempDir()
+	from := filepath.Join(root, "test-eu-central-1", "app")
+	mustMkdirAll(t, from)
+
+	_, err := envpath.Switch(from, "prod")
+	if err == nil {
+		t.Fatalf("expected error when neither prod-eu-central-1 nor prod exists")
+	}
+	if !strings.Contains(err.Error(), `"prod-eu-central-1"`) {
+		t.Fatalf("expected error to name the composed environment, got %v", err)
+	}
+}
+
+func TestSwitchCompoundKeepsFullTargetNames(t *testing.T) {
+	setupCompoundConfig(t)
+
+	root := t.TempDir()
+	from := filepath.Join(root, "test-eu-central-1", "app")
+	mustMkdirAll(t, from)
+
+	target, err := envpath.Switch(from, "prod-us-east-1")
+	if err != nil {
+		t.Fatalf("Switch returned error: %v", err)
+	}
+	if expected := filepath.Join(root, "prod-us-east-1", "app"); target != expected {
+		t.Fatalf("expected %q, got %q", expected, target)
+	}
+}
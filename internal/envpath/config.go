@@ -0,0 +1,760 @@
+package envpath
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var defaultEnvs = []string{"dev", "test", "prod"}
+
+const (
+	// repoConfigName is the per-repository environment list discovered by
+	// walking up from the working directory.
+	repoConfigName = ".cenv"
+	// repoTOMLConfigName is the structured per-repository configuration.
+	repoTOMLConfigName = ".cenv.toml"
+)
+
+// Config is the merged changeenv configuration.
+type Config struct {
+	// Envs lists the known environments in order.
+	Envs []Env
+	// Patterns recognise additional environment directories when detecting
+	// the current environment. They cannot be used as switch targets.
+	Patterns []Pattern
+	// Compound decomposes environment names into components so that a
+	// partial target replaces only its component. It is nil when unset.
+	Compound *Compound
+	// Layouts are path templates with named dimensions, tried in order.
+	Layouts []Layout
+	// Ignore excludes directories from environment detection, in order.
+	Ignore []IgnoreRule
+	// Rules rewrite the path below the environment directory, in order.
+	Rules []Rule
+	// RootMarkers replaces the default repository root markers when set.
+	RootMarkers Setting[[]string]
+	// EnvMarker names the file that declares its directory an environment
+	// root. It is empty when unset, meaning ".cenv-env".
+	EnvMarker Setting[string]
+	// Case controls how names and directories are compared while resolving.
+	// It is empty when unset, meaning CaseInsensitive.
+	Case Setting[CaseMode]
+	// Occurrence selects the environment segment to switch when a path
+	// contains several. It is empty when unset.
+	Occurrence Setting[Occurrence]
+	// TokenBoundaries lists the characters that may surround an environment
+	// token in a file name. It is empty when unset, meaning "-_.".
+	TokenBoundaries Setting[string]
+	// Files lists the configuration files that were applied, in order.
+	Files []string
+	// Issues collects problems that were skipped while loading, such as
+	// unreadable files.
+	Issues []Issue
+
+	declarations []declaration
+}
+
+// Setting is a configuration value together with where it was defined. A zero
+// Origin means the built-in default is in effect.
+type Setting[T any] struct {
+	Value  T
+	Origin Origin
+}
+
+// Issue is a problem found in the configuration.
+type Issue struct {
+	Origin  Origin
+	Message string
+}
+
+func (i Issue) String() string {
+	return i.Origin.String() + ": " + i.Message
+}
+
+// declaration records every place an environment name was declared so that
+// Check can report duplicates after the sources have been merged.
+type declaration struct {
+	name   string
+	origin Origin
+	// reopen is set for structured config tables, which may extend an
+	// existing environment without being a duplicate.
+	reopen bool
+}
+
+// Env is a named environment.
+type Env struct {
+	Name    string
+	Aliases []Alias
+	// Dirs lists the directory names used for the environment on disk. The
+	// first entry is written when switching to the environment. When empty,
+	// the directory is named like the environment.
+	Dirs        Setting[[]string]
+	Description Setting[string]
+	Meta        map[string]Setting[string]
+	Origin      Origin
+}
+
+// Alias is an alternative name for an environment.
+type Alias struct {
+	Name   string
+	Origin Origin
+}
+
+// Origin records where a configuration entry was defined.
+type Origin struct {
+	// File is the configuration file, empty for variables and defaults.
+	File string
+	// Line is the 1-based line within File, or 0 if unknown.
+	Line int
+	// Variable is the environment variable the entry came from.
+	Variable string
+}
+
+func (o Origin) String() string {
+	switch {
+	case o.Variable != "":
+		return "$" + o.Variable
+	case o.File != "" && o.Line > 0:
+		return fmt.Sprintf("%s:%d", o.File, o.Line)
+	case o.File != "":
+		return o.File
+	default:
+		return "built-in"
+	}
+}
+
+// DirNames returns the directory names that identify the environment on disk.
+func (e *Env) DirNames() []string {
+	if len(e.Dirs.Value) > 0 {
+		return e.Dirs.Value
+	}
+	return []string{e.Name}
+}
+
+// Dir returns the directory name written when switching to the environment.
+func (e *Env) Dir() string {
+	return e.DirNames()[0]
+}
+
+// Names returns the environment names in order.
+func (c *Config) Names() []string {
+	names := make([]string, len(c.Envs))
+	for i, env := range c.Envs {
+		names[i] = env.Name
+	}
+	return names
+}
+
+// EnvForDir returns the environment whose directory names include dir,
+// compared according to the case mode.
+func (c *Config) EnvForDir(dir string) (*Env, bool) {
+	for i := range c.Envs {
+		for _, name := range c.Envs[i].DirNames() {
+			if c.sameSegment(name, dir) {
+				return &c.Envs[i], true
+			}
+		}
+	}
+	return nil, false
+}
+
+// envNameForDir returns the name of the environment stored in dir, or dir
+// itself if no configured environment uses it.
+func (c *Config) envNameForDir(dir string) string {
+	if env, ok := c.EnvForDir(dir); ok {
+		return env.Name
+	}
+	return dir
+}
+
+// Lookup returns the environment called name, compared according to the case
+// mode like a target: exactly when the mode is sensitive, or smart and name
+// contains upper-case letters.
+func (c *Config) Lookup(name string) (*Env, bool) {
+	fold := c.foldTarget(name)
+	for i := range c.Envs {
+		if c.Envs[i].Name == name || fold && strings.EqualFold(c.Envs[i].Name, name) {
+			return &c.Envs[i], true
+		}
+	}
+	return nil, false
+}
+
+// LoadConfig returns the configuration for dir. Sources are applied in
+// increasing order of precedence:
+// 1. Built-in defaults (dev, test, prod)
+// 2. Custom environments from ~/.cenvrc (one per line)
+// 3. The user config file: configPath, $CENV_CONFIG, or
+// $XDG_CONFIG_HOME/changeenv/config.toml
+// 4. .cenv and .cenv.toml files in dir and its ancestors, outermost first
+// 5. Custom environments from $CENV_ENVIRONMENTS (space-separated)
+//
+// Lines in ~/.cenvrc and .cenv files may start with "!" to remove an inherited
+// environment or pattern ("!staging") or everything inherited ("!*"). Entries
+// that look like globs or regular expressions become patterns.
+func LoadConfig(dir, configPath string) (*Config, error) {
+	return loadConfig(dir, configPath, false)
+}
+
+// LoadConfigForCheck loads the configuration like LoadConfig, but records
+// structured config files that cannot be read or parsed as Issues instead of
+// failing, so that Check reports them along with every other problem.
+func LoadConfigForCheck(dir, configPath string) (*Config, error) {
+	return loadConfig(dir, configPath, true)
+}
+
+// loadConfig implements LoadConfig, recording structured config failures as
+// issues when lenient is set.
+func loadConfig(dir, configPath string, lenient bool) (*Config, error) {
+	cfg := &Config{}
+	for _, name := range defaultEnvs {
+		cfg.addEnv(name, Origin{}, false)
+	}
+
+	homeDir, homeErr := os.UserHomeDir()
+
+	// Load from ~/.cenvrc if it exists
+	if homeErr == nil {
+		cfg.applyEnvFile(filepath.Join(homeDir, ".cenvrc"))
+	}
+
+	userConfig, explicit := userConfigPath(configPath, homeDir)
+	if userConfig != "" {
+		if err := cfg.applyTOMLFile(userConfig, explicit); err != nil && !cfg.recordIssue(err, userConfig, lenient) {
+			return nil, err
+		}
+	}
+
+	// Load .cenv files from the outermost ancestor down to dir
+	repoConfigs := findRepoConfigs(dir)
+	for i := len(repoConfigs) - 1; i >= 0; i-- {
+		path := repoConfigs[i]
+		if filepath.Base(path) == repoTOMLConfigName {
+			if err := cfg.applyTOMLFile(path, false); err != nil && !cfg.recordIssue(err, path, lenient) {
+				return nil, err
+			}
+			continue
+		}
+		cfg.applyEnvFile(path)
+	}
+
+	// Load from $CENV_ENVIRONMENTS if set
+	if cenvEnvs := os.Getenv("CENV_ENVIRONMENTS"); cenvEnvs != "" {
+		for _, env := range strings.Fields(cenvEnvs) {
+			cfg.applyEnvEntry(env, Origin{Variable: "CENV_ENVIRONMENTS"})
+		}
+	}
+
+	return cfg, nil
+}
+
+// userConfigPath returns the user configuration file and whether it was
+// requested explicitly, in which case it must exist.
+func userConfigPath(configPath, homeDir string) (string, bool) {
+	if configPath != "" {
+		return configPath, true
+	}
+	if fromEnv := os.Getenv("CENV_CONFIG"); fromEnv != "" {
+		return fromEnv, true
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if homeDir == "" {
+			return "", false
+		}
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "changeenv", "config.toml"), false
+}
+
+// findRepoConfigs returns the .cenv and .cenv.toml files in dir and its
+// ancestors, nearest first. Within a directory .cenv.toml precedes .cenv so
+// that it is applied last.
+func findRepoConfigs(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+	var found []string
+	dir = filepath.Clean(dir)
+	for {
+		for _, name := range []string{repoTOMLConfigName, repoConfigName} {
+			candidate := filepath.Join(dir, name)
+			if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+				found = append(found, candidate)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return found
+		}
+		dir = parent
+	}
+}
+
+func (c *Config) applyEnvFile(path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			c.Issues = append(c.Issues, Issue{Origin: Origin{File: path}, Message: fmt.Sprintf("cannot read file: %v", err)})
+		}
+		return
+	}
+	defer file.Close()
+	c.Files = append(c.Files, path)
+	entries, err := parseEnvList(file)
+	if err != nil {
+		c.Issues = append(c.Issues, Issue{Origin: Origin{File: path}, Message: fmt.Sprintf("cannot read file: %v", err)})
+	}
+	for _, entry := range entries {
+		c.applyEnvEntry(entry.text, Origin{File: path, Line: entry.line})
+	}
+}
+
+type envEntry struct {
+	text string
+	line int
+}
+
+// parseEnvList reads one environment entry per line, skipping blank lines and
+// comments.
+func parseEnvList(r io.Reader) ([]envEntry, error) {
+	var entries []envEntry
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		// Skip empty lines and comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// Remove inline comments
+		if idx := strings.Index(line, "#"); idx != -1 {
+			line = strings.TrimSpace(line[:idx])
+		}
+		if line != "" {
+			entries = append(entries, envEntry{text: line, line: lineNo})
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// applyEnvEntry adds the environment named by entry, or removes inherited
+// environments when the entry starts with "!".
+func (c *Config) applyEnvEntry(entry string, origin Origin) {
+	entry = strings.TrimSpace(entry)
+	name, remove := strings.CutPrefix(entry, "!")
+	if !remove {
+		switch {
+		case entry == "":
+		case isPattern(entry):
+			c.Patterns = append(c.Patterns, newPattern(entry, origin))
+		default:
+			c.addEnv(entry, origin, false)
+		}
+		return
+	}
+	name = strings.TrimSpace(name)
+	if name == "*" {
+		c.Envs = nil
+		c.Patterns = nil
+		c.declarations = nil
+		return
+	}
+	c.Patterns = slices.DeleteFunc(c.Patterns, func(p Pattern) bool {
+		return p.Raw == name
+	})
+	c.Envs = slices.DeleteFunc(c.Envs, func(env Env) bool {
+		return strings.EqualFold(env.Name, name)
+	})
+	c.declarations = slices.DeleteFunc(c.declarations, func(decl declaration) bool {
+		return strings.EqualFold(decl.name, name)
+	})
+}
+
+// addEnv returns the environment called name, appending it if it is not yet
+// known. reopen marks declarations that may legitimately extend an existing
+// environment.
+func (c *Config) addEnv(name string, origin Origin, reopen bool) *Env {
+	c.declarations = append(c.declarations, declaration{name: name, origin: origin, reopen: reopen})
+	if env, ok := c.Lookup(name); ok {
+		return env
+	}
+	c.Envs = append(c.Envs, Env{Name: name, Origin: origin})
+	return &c.Envs[len(c.Envs)-1]
+}
+
+// recordIssue records err, returned by applyTOMLFile for path, as an issue
+// when lenient is set, and reports whether it did.
+func (c *Config) recordIssue(err error, path string, lenient bool) bool {
+	if !lenient {
+		return false
+	}
+	var iErr *issueError
+	if errors.As(err, &iErr) {
+		c.Issues = append(c.Issues, iErr.Issue)
+	} else {
+		c.Issues = append(c.Issues, Issue{Origin: Origin{File: path}, Message: fmt.Sprintf("cannot read file: %v", errors.Unwrap(err))})
+	}
+	return true
+}
+
+// issueError is an error at a known place in a configuration file.
+type issueError struct {
+	Issue
+}
+
+func (e *issueError) Error() string {
+	return e.Issue.String()
+}
+
+// applyTOMLFile merges the structured configuration file at path. A missing
+// file is only an error when required is set.
+func (c *Config) applyTOMLFile(path string, required bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !required && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("read config: %w", err)
+	}
+	root, err := parseTOML(string(data))
+	if err != nil {
+		origin := Origin{File: path}
+		var tErr *tomlError
+		if errors.As(err, &tErr) {
+			origin.Line = tErr.line
+			return &issueError{Issue{Origin: origin, Message: tErr.msg}}
+		}
+		return &issueError{Issue{Origin: origin, Message: err.Error()}}
+	}
+	c.Files = append(c.Files, path)
+	d := &configDecoder{path: path, cfg: c}
+	return d.decode(root)
+}
+
+// configDecoder applies a parsed TOML file to a Config.
+type configDecoder struct {
+	path string
+	cfg  *Config
+}
+
+func (d *configDecoder) errorf(line int, format string, args ...any) error {
+	return &issueError{Issue{Origin: d.origin(line), Message: fmt.Sprintf(format, args...)}}
+}
+
+func (d *configDecoder) origin(line int) Origin {
+	return Origin{File: d.path, Line: line}
+}
+
+func (d *configDecoder) decode(root *tomlTable) error {
+	var order *tomlValue
+	for _, key := range root.keys {
+		value := root.values[key]
+		switch key {
+		case "order":
+			order = value
+		case "root_markers":
+			markers, err := d.stringList(key, value)
+			if err != nil {
+				return err
+			}
+			d.cfg.RootMarkers = Setting[[]string]{Value: markers, Origin: d.origin(value.line)}
+		case "env_marker":
+			name, err := d.string(key, value)
+			if err != nil {
+				return err
+			}
+			if name = strings.TrimSpace(name); name == "" || hasPathSeparator(name) {
+				return d.errorf(value.line, "%s must be a file name", key)
+			}
+			d.cfg.EnvMarker = Setting[string]{Value: name, Origin: d.origin(value.line)}
+		case "case":
+			raw, err := d.string(key, value)
+			if err != nil {
+				return err
+			}
+			mode, err := ParseCaseMode(raw)
+			if err != nil {
+				return d.errorf(value.line, "%v", err)
+			}
+			d.cfg.Case = Setting[CaseMode]{Value: mode, Origin: d.origin(value.line)}
+		case "occurrence":
+			raw, err := d.string(key, value)
+			if err != nil {
+				return err
+			}
+			occurrence, err := ParseOccurrence(raw)
+			if err != nil {
+				return d.errorf(value.line, "%v", err)
+			}
+			d.cfg.Occurrence = Setting[Occurrence]{Value: occurrence, Origin: d.origin(value.line)}
+		case "token_boundaries":
+			boundaries, err := d.string(key, value)
+			if err != nil {
+				return err
+			}
+			if boundaries == "" || strings.ContainsFunc(boundaries, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+				return d.errorf(value.line, "%s must be a non-empty string without letters or digits", key)
+			}
+			d.cfg.TokenBoundaries = Setting[string]{Value: boundaries, Origin: d.origin(value.line)}
+		case "patterns":
+			items, ok := value.value.([]*tomlValue)
+			if !ok {
+				return d.errorf(value.line, "%s must be an array of strings", key)
+			}
+			for _, item := range items {
+				raw, err := d.string(key, item)
+				if err != nil {
+					return err
+				}
+				d.cfg.Patterns = append(d.cfg.Patterns, newPattern(raw, d.origin(item.line)))
+			}
+		case "ignore":
+			items, ok := value.value.([]*tomlValue)
+			if !ok {
+				return d.errorf(value.line, "%s must be an array of strings", key)
+			}
+			for _, item := range items {
+				raw, err := d.string(key, item)
+				if err != nil {
+					return err
+				}
+				d.cfg.Ignore = append(d.cfg.Ignore, newIgnoreRule(raw, d.origin(item.line)))
+			}
+		case "compound":
+			table, err := d.table(key, value)
+			if err != nil {
+				return err
+			}
+			for _, field := range table.keys {
+				if field != "pattern" {
+					return d.errorf(table.values[field].line, "unknown key %q", key+"."+field)
+				}
+				raw, err := d.string(key+"."+field, table.values[field])
+				if err != nil {
+					return err
+				}
+				d.cfg.Compound = newCompound(raw, d.origin(table.values[field].line))
+			}
+		case "layout":
+			tables, ok := value.value.([]*tomlTable)
+			if !ok {
+				return d.errorf(value.line, "%s must be an array of tables ([[layout]])", key)
+			}
+			for _, table := range tables {
+				if err := d.decodeLayout(table); err != nil {
+					return err
+				}
+			}
+		case "rewrite":
+			tables, ok := value.value.([]*tomlTable)
+			if !ok {
+				return d.errorf(value.line, "%s must be an array of tables ([[rewrite]])", key)
+			}
+			for _, table := range tables {
+				if err := d.decodeRule(table); err != nil {
+					return err
+				}
+			}
+		case "env":
+			envs, err := d.table(key, value)
+			if err != nil {
+				return err
+			}
+			for _, name := range envs.keys {
+				if err := d.decodeEnv(name, envs.values[name]); err != nil {
+					return err
+				}
+			}
+		default:
+			return d.errorf(value.line, "unknown key %q", key)
+		}
+	}
+	if order != nil {
+		names, err := d.stringList("order", order)
+		if err != nil {
+			return err
+		}
+		d.cfg.applyOrder(names)
+	}
+	return nil
+}
+
+func (d *configDecoder) decodeEnv(name string, value *tomlValue) error {
+	table, err := d.table("env."+name, value)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(name) == "" {
+		return d.errorf(value.line, "environment name must not be empty")
+	}
+	env := d.cfg.addEnv(name, d.origin(value.line), true)
+	for _, key := range table.keys {
+		field := table.values[key]
+		qualified := "env." + name + "." + key
+		switch key {
+		case "aliases":
+			aliases, err := d.stringList(qualified, field)
+			if err != nil {
+				return err
+			}
+			for _, alias := range aliases {
+				env.addAlias(alias, d.origin(field.line))
+			}
+		case "dirs":
+			dirs, err := d.stringList(qualified, field)
+			if err != nil {
+				return err
+			}
+			if len(dirs) == 0 {
+				return d.errorf(field.line, "%s must not be empty", qualified)
+			}
+			env.Dirs = Setting[[]string]{Value: dirs, Origin: d.origin(field.line)}
+		case "description":
+			description, err := d.string(qualified, field)
+			if err != nil {
+				return err
+			}
+			env.Description = Setting[string]{Value: description, Origin: d.origin(field.line)}
+		case "meta":
+			meta, err := d.table(qualified, field)
+			if err != nil {
+				return err
+			}
+			for _, metaKey := range meta.keys {
+				metaField := meta.values[metaKey]
+				metaValue, err := d.string(qualified+"."+metaKey, metaField)
+				if err != nil {
+					return err
+				}
+				if env.Meta == nil {
+					env.Meta = make(map[string]Setting[string])
+				}
+				env.Meta[metaKey] = Setting[string]{Value: metaValue, Origin: d.origin(metaField.line)}
+			}
+		default:
+			return d.errorf(field.line, "unknown key %q", qualified)
+		}
+	}
+	return nil
+}
+
+func (d *configDecoder) decodeLayout(table *tomlTable) error {
+	field, ok := table.values["template"]
+	if !ok {
+		return d.errorf(table.line, "layout requires a template")
+	}
+	for _, key := range table.keys {
+		if key != "template" {
+			return d.errorf(table.values[key].line, "unknown key %q", "layout."+key)
+		}
+	}
+	template, err := d.string("layout.template", field)
+	if err != nil {
+		return err
+	}
+	layout, err := parseLayout(template, d.origin(field.line))
+	if err != nil {
+		return d.errorf(field.line, "%v", err)
+	}
+	d.cfg.Layouts = append(d.cfg.Layouts, layout)
+	return nil
+}
+
+func (d *configDecoder) decodeRule(table *tomlTable) error {
+	fields := make(map[string]string)
+	for _, key := range table.keys {
+		switch key {
+		case "from", "to", "match", "replace":
+			value, err := d.string("rewrite."+key, table.values[key])
+			if err != nil {
+				return err
+			}
+			fields[key] = value
+		default:
+			return d.errorf(table.values[key].line, "unknown key %q", "rewrite."+key)
+		}
+	}
+	if _, ok := fields["match"]; !ok {
+		return d.errorf(table.line, "rewrite requires match")
+	}
+	if _, ok := fields["replace"]; !ok {
+		return d.errorf(table.line, "rewrite requires replace")
+	}
+	rule, err := newRule(fields["from"], fields["to"], fields["match"], fields["replace"], d.origin(table.line))
+	if err != nil {
+		return d.errorf(table.values["match"].line, "%v", err)
+	}
+	d.cfg.Rules = append(d.cfg.Rules, rule)
+	return nil
+}
+
+func (d *configDecoder) table(key string, value *tomlValue) (*tomlTable, error) {
+	table, ok := value.value.(*tomlTable)
+	if !ok {
+		return nil, d.errorf(value.line, "%s must be a table", key)
+	}
+	return table, nil
+}
+
+func (d *configDecoder) string(key string, value *tomlValue) (string, error) {
+	s, ok := value.value.(string)
+	if !ok {
+		return "", d.errorf(value.line, "%s must be a string", key)
+	}
+	return s, nil
+}
+
+func (d *configDecoder) stringList(key string, value *tomlValue) ([]string, error) {
+	items, ok := value.value.([]*tomlValue)
+	if !ok {
+		return nil, d.errorf(value.line, "%s must be an array of strings", key)
+	}
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.value.(string)
+		if !ok {
+			return nil, d.errorf(item.line, "%s must be an array of strings", key)
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+func (e *Env) addAlias(name string, origin Origin) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return
+	}
+	for _, alias := range e.Aliases {
+		if strings.EqualFold(alias.Name, name) {
+			return
+		}
+	}
+	e.Aliases = append(e.Aliases, Alias{Name: name, Origin: origin})
+}
+
+// applyOrder moves the named environments to the front in the given order.
+// Environments that are not listed keep their relative order.
+func (c *Config) applyOrder(names []string) {
+	rank := func(env Env) int {
+		for i, name := range names {
+			if strings.EqualFold(env.Name, name) {
+				return i
+			}
+		}
+		return len(names)
+	}
+	slices.SortStableFunc(c.Envs, func(a, b Env) int {
+		return rank(a) - rank(b)
+	})
+}
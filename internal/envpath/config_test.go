@@ -0,0 +1,135 @@
+package envpath
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolateConfig points every configuration source at empty temporary
+// locations and returns the home directory.
+func isolateConfig(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("CENV_CONFIG", "")
+	t.Setenv("CENV_ENVIRONMENTS", "")
+	t.Setenv("CENV_ROOT_MARKERS", "")
+	return home
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create %q: %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %q: %v", path, err)
+	}
+}
+
+func TestLoadKnownEnvsMergesSourcesWithProvenance(t *testing.T) {
+	home := isolateConfig(t)
+	writeFile(t, filepath.Join(home, ".cenvrc"), "staging\n")
+	userConfig := filepath.Join(home, ".config", "changeenv", "config.toml")
+	writeFile(t, userConfig, `order = ["prod", "staging"]
+
+[env.prod]
+aliases = ["production", "prd"]
+description = "Production"
+
+[env.prod.meta]
+owner = "platform"
+
+[env.canary]
+`)
+	repo := t.TempDir()
+	writeFile(t, filepath.Join(repo, ".cenv.toml"), "[env.prod]\naliases = [\"live\"]\n")
+	t.Setenv("CENV_ENVIRONMENTS", "qa")
+
+	cfg, err := LoadConfig(repo, "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+
+	if got := strings.Join(cfg.Names(), ","); got != "prod,staging,dev,test,canary,qa" {
+		t.Fatalf("unexpected environment order %q", got)
+	}
+
+	prod, ok := cfg.Lookup("PROD")
+	if !ok {
+		t.Fatalf("expected case-insensitive lookup to find prod")
+	}
+	if prod.Origin.String() != "built-in" {
+		t.Fatalf("expected prod to originate from defaults, got %s", prod.Origin)
+	}
+	var aliases []string
+	for _, alias := range prod.Aliases {
+		aliases = append(aliases, alias.Name)
+	}
+	if got := strings.Join(aliases, ","); got != "production,prd,live" {
+		t.Fatalf("unexpected aliases %q", got)
+	}
+	if got := prod.Aliases[2].Origin.String(); got != filepath.Join(repo, ".cenv.toml")+":2" {
+		t.Fatalf("unexpected alias origin %q", got)
+	}
+	if prod.Description.Value != "Production" || prod.Meta["owner"].Value != "platform" {
+		t.Fatalf("unexpected metadata %+v", prod)
+	}
+
+	staging, _ := cfg.Lookup("staging")
+	if got := staging.Origin.String(); got != filepath.Join(home, ".cenvrc")+":1" {
+		t.Fatalf("unexpected staging origin %q", got)
+	}
+	canary, _ := cfg.Lookup("canary")
+	if got := canary.Origin.String(); got != userConfig+":10" {
+		t.Fatalf("unexpected canary origin %q", got)
+	}
+	qa, _ := cfg.Lookup("qa")
+	if got := qa.Origin.String(); got != "$CENV_ENVIRONMENTS" {
+		t.Fatalf("unexpected qa origin %q", got)
+	}
+}
+
+func TestLoadKnownEnvsUsesExplicitConfigPath(t *testing.T) {
+	home := isolateConfig(t)
+	explicit := filepath.Join(home, "custom.toml")
+	writeFile(t, explicit, "root_markers = [\".cenv-root\"]\n[env.sandbox]\n")
+	t.Setenv("CENV_CONFIG", filepath.Join(home, "ignored.toml"))
+
+	cfg, err := LoadConfig(t.TempDir(), explicit)
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if _, ok := cfg.Lookup("sandbox"); !ok {
+		t.Fatalf("expected sandbox from explicit config")
+	}
+	if got := strings.Join(cfg.RootMarkers.Value, ","); got != ".cenv-root" {
+		t.Fatalf("unexpected root markers %q", got)
+	}
+}
+
+func TestLoadKnownEnvsRejectsMissingExplicitConfig(t *testing.T) {
+	home := isolateConfig(t)
+	t.Setenv("CENV_CONFIG", filepath.Join(home, "missing.toml"))
+
+	if _, err := LoadConfig(t.TempDir(), ""); err == nil {
+		t.Fatalf("expected error for missing $CENV_CONFIG file")
+	}
+}
+
+func TestLoadKnownEnvsReportsUnknownKeys(t *testing.T) {
+	home := isolateConfig(t)
+	path := filepath.Join(home, ".config", "changeenv", "config.toml")
+	writeFile(t, path, "[env.prod]\nalias = [\"p\"]\n")
+
+	_, err := LoadConfig(t.TempDir(), "")
+	if err == nil {
+		t.Fatalf("expected error for unknown key")
+	}
+	if !strings.Contains(err.Error(), path+":2:") {
+		t.Fatalf("expected error to reference %s:2, got %v", path, err)
+	}
+}
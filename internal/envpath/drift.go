@@ -0,0 +1,222 @@
+package envpath
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DriftBaselineName is the default baseline file, stored in the directory that
+// holds the environment directories.
+const DriftBaselineName = ".cenv-drift"
+
+// DriftEntry is a file that exists in some environment but is missing from
+// Env.
+type DriftEntry struct {
+	// Env is the environment missing the file.
+	Env string
+	// Path is the slash-separated location of the missing file relative to
+	// the root of Env.
+	Path string
+	// Source is the first environment the file was found in.
+	Source string
+}
+
+// DriftReport lists the files missing from some environment trees.
+type DriftReport struct {
+	// BaseDir is the directory holding the current environment directory.
+	BaseDir string
+	// Envs lists the environments whose trees were compared.
+	Envs []string
+	// Missing lists every missing file, ordered by environment and path.
+	Missing []DriftEntry
+}
+
+// Drift compares file presence across the environment trees next to the
+// environment that path lies in. Every file of every tree is mapped into the
+// other trees like Resolve maps directories, so rewrite rules and marker
+// roots apply. Only known environments are compared, see Counterpart.Known;
+// environments without a tree on disk and hidden files are skipped.
+func Drift(path string, opts Options) (*DriftReport, error) {
+	if path == "" {
+		return nil, errors.New("path must not be empty")
+	}
+	cleanPath := filepath.Clean(path)
+	cfg, err := opts.config(cleanPath)
+	if err != nil {
+		return nil, err
+	}
+	policy, _, err := occurrencePolicy(opts.Occurrence, cfg)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := locate(cleanPath, cfg, opts, policy, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if loc.envIndex == -1 {
+		return nil, loc.notInEnvError(cleanPath)
+	}
+	envRoot := loc.path(loc.parts[:loc.envIndex+loc.envLen])
+	list, err := counterparts(envRoot, cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &DriftReport{BaseDir: loc.path(loc.parts[:loc.envIndex])}
+	var trees []Counterpart
+	for _, counterpart := range list {
+		if counterpart.Known && counterpart.Err == nil && counterpart.Exists {
+			trees = append(trees, counterpart)
+			report.Envs = append(report.Envs, counterpart.Env)
+		}
+	}
+
+	// resolved caches the counterpart of a directory in an environment.
+	type dirEnv struct{ dir, env string }
+	resolved := make(map[dirEnv]string)
+	counterpartDir := func(dir, env string) string {
+		key := dirEnv{dir, env}
+		if target, ok := resolved[key]; ok {
+			return target
+		}
+		target := ""
+		if result, err := resolve(dir, env, opts, cfg, nil); err == nil {
+			target = result.Target
+		}
+		resolved[key] = target
+		return target
+	}
+
+	seen := make(map[DriftEntry]bool)
+	for _, source := range trees {
+		err := walkFiles(source.Path, func(file string) {
+			for _, target := range trees {
+				if target.Env == source.Env {
+					continue
+				}
+				dir := counterpartDir(filepath.Dir(file), target.Env)
+				if dir == "" {
+					continue
+				}
+				counterpart := filepath.Join(dir, filepath.Base(file))
+				if _, err := os.Lstat(counterpart); err == nil {
+					continue
+				}
+				rel, err := filepath.Rel(target.Path, counterpart)
+				if err != nil {
+					continue
+				}
+				entry := DriftEntry{Env: target.Env, Path: filepath.ToSlash(rel)}
+				if !seen[entry] {
+					seen[entry] = true
+					entry.Source = source.Env
+					report.Missing = append(report.Missing, entry)
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	slices.SortStableFunc(report.Missing, func(a, b DriftEntry) int {
+		if a.Env != b.Env {
+			return slices.Index(report.Envs, a.Env) - slices.Index(report.Envs, b.Env)
+		}
+		return strings.Compare(a.Path, b.Path)
+	})
+	return report, nil
+}
+
+// walkFiles calls fn for every file below root, skipping hidden entries.
+func walkFiles(root string, fn func(file string)) error {
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !entry.IsDir() {
+			fn(path)
+		}
+		return nil
+	})
+}
+
+// Unexpected splits the missing files into those not listed in baseline and
+// the baseline entries that no longer drift.
+func (r *DriftReport) Unexpected(baseline []DriftEntry) (unexpected, stale []DriftEntry) {
+	allowed := make(map[DriftEntry]bool, len(baseline))
+	for _, entry := range baseline {
+		allowed[DriftEntry{Env: entry.Env, Path: entry.Path}] = true
+	}
+	current := make(map[DriftEntry]bool, len(r.Missing))
+	for _, entry := range r.Missing {
+		key := DriftEntry{Env: entry.Env, Path: entry.Path}
+		current[key] = true
+		if !allowed[key] {
+			unexpected = append(unexpected, entry)
+		}
+	}
+	for _, entry := range baseline {
+		if !current[DriftEntry{Env: entry.Env, Path: entry.Path}] {
+			stale = append(stale, entry)
+		}
+	}
+	return unexpected, stale
+}
+
+// ReadDriftBaseline parses a baseline file. Each line holds an environment
+// name and a path separated by a space; blank lines and lines starting with
+// "#" are skipped. A missing file is an empty baseline.
+func ReadDriftBaseline(path string) ([]DriftEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read drift baseline: %w", err)
+	}
+	defer f.Close()
+
+	var entries []DriftEntry
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		env, rel, ok := strings.Cut(text, " ")
+		rel = strings.TrimSpace(rel)
+		if !ok || rel == "" {
+			return nil, fmt.Errorf("%s:%d: expected \"<env> <path>\"", path, line)
+		}
+		entries = append(entries, DriftEntry{Env: env, Path: rel})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read drift baseline: %w", err)
+	}
+	return entries, nil
+}
+
+// WriteBaseline writes the missing files of r in the baseline format.
+func (r *DriftReport) WriteBaseline(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Files allowed to be missing from an environment, as \"<env> <path>\".")
+	fmt.Fprintln(bw, "# Regenerate with: changeenv drift --update-baseline")
+	for _, entry := range r.Missing {
+		fmt.Fprintf(bw, "%s %s\n", entry.Env, entry.Path)
+	}
+	return bw.Flush()
+}
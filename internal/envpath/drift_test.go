@@ -0,0 +1,128 @@
+package envpath_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+// driftFiles are the files of the repository the drift tests compare.
+var driftFiles = []string{
+	"dev/app/values.yaml",
+	"dev/app/debug.yaml",
+	"dev/app/.scratch",
+	"prod/app/values.yaml",
+	"prod/alerts.yaml",
+}
+
+func TestDriftReportsMissingFiles(t *testing.T) {
+	repo := setupRepo(t, "", driftFiles...)
+
+	report, err := envpath.Drift(filepath.Join(repo, "dev", "app"), envpath.Options{})
+	if err != nil {
+		t.Fatalf("Drift returned error: %v", err)
+	}
+	if report.BaseDir != repo || strings.Join(report.Envs, ",") != "dev,prod" {
+		t.Fatalf("unexpected base %q or envs %q", report.BaseDir, report.Envs)
+	}
+	expected := []envpath.DriftEntry{
+		{Env: "dev", Path: "alerts.yaml", Source: "prod"},
+		{Env: "prod", Path: "app/debug.yaml", Source: "dev"},
+	}
+	if len(report.Missing) != len(expected) {
+		t.Fatalf("expected %+v, got %+v", expected, report.Missing)
+	}
+	for i, want := range expected {
+		if report.Missing[i] != want {
+			t.Fatalf("entry %d: expected %+v, got %+v", i, want, report.Missing[i])
+		}
+	}
+}
+
+func TestDriftSkipsDirectoriesThatAreNotEnvironments(t *testing.T) {
+	files := append([]string{"docs/index.md", "scripts/", "qa-1/app/values.yaml"}, driftFiles...)
+	repo := setupRepo(t, "patterns = [\"qa-*\"]\n", files...)
+
+	report, err := envpath.Drift(filepath.Join(repo, "dev", "app"), envpath.Options{})
+	if err != nil {
+		t.Fatalf("Drift returned error: %v", err)
+	}
+	if got := strings.Join(report.Envs, ","); got != "dev,prod,qa-1" {
+		t.Fatalf("unexpected envs %q", got)
+	}
+	for _, entry := range report.Missing {
+		if entry.Env == "docs" || entry.Env == "scripts" || entry.Source == "docs" {
+			t.Fatalf("unexpected entry for a non-environment directory: %+v", entry)
+		}
+	}
+}
+
+func TestDriftFollowsRewriteRules(t *testing.T) {
+	repo := setupRepo(t, `[[rewrite]]
+from = "dev"
+to = "prod"
+match = '^app(/|$)'
+replace = 'application${1}'
+
+[[rewrite]]
+from = "prod"
+to = "dev"
+match = '^application(/|$)'
+replace = 'app${1}'
+`, "dev/app/values.yaml", "prod/application/values.yaml")
+
+	report, err := envpath.Drift(filepath.Join(repo, "dev"), envpath.Options{})
+	if err != nil {
+		t.Fatalf("Drift returned error: %v", err)
+	}
+	if len(report.Missing) != 0 {
+		t.Fatalf("expected rewritten directories to match, got %+v", report.Missing)
+	}
+}
+
+func TestDriftBaselineRoundTrip(t *testing.T) {
+	repo := setupRepo(t, "", driftFiles...)
+	report, err := envpath.Drift(filepath.Join(repo, "prod"), envpath.Options{})
+	if err != nil {
+		t.Fatalf("Drift returned error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteBaseline(&buf); err != nil {
+		t.Fatalf("WriteBaseline returned error: %v", err)
+	}
+	baselinePath := filepath.Join(repo, envpath.DriftBaselineName)
+	envpath.WriteFile(t, baselinePath, buf.String()+"prod gone.yaml\n")
+
+	baseline, err := envpath.ReadDriftBaseline(baselinePath)
+	if err != nil {
+		t.Fatalf("ReadDriftBaseline returned error: %v", err)
+	}
+	unexpected, stale := report.Unexpected(baseline)
+	if len(unexpected) != 0 {
+		t.Fatalf("expected the baseline to allow all drift, got %+v", unexpected)
+	}
+	if len(stale) != 1 || stale[0].Path != "gone.yaml" {
+		t.Fatalf("expected one stale entry, got %+v", stale)
+	}
+
+	unexpected, _ = report.Unexpected(baseline[:1])
+	if len(unexpected) != 1 || unexpected[0].Path != "app/debug.yaml" {
+		t.Fatalf("expected app/debug.yaml to be unexpected, got %+v", unexpected)
+	}
+}
+
+func TestReadDriftBaselineRejectsMalformedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), envpath.DriftBaselineName)
+	envpath.WriteFile(t, path, "# comment\n\nprod\n")
+
+	if _, err := envpath.ReadDriftBaseline(path); err == nil || !strings.Contains(err.Error(), path+":3:") {
+		t.Fatalf("expected error naming line 3, got %v", err)
+	}
+	if entries, err := envpath.ReadDriftBaseline(path + ".missing"); err != nil || entries != nil {
+		t.Fatalf("expected a missing baseline to be empty, got %v, %v", entries, err)
+	}
+}
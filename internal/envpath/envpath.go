@@ -1,108 +1,344 @@
 package envpath
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
-var defaultEnvs = []string{"dev", "test", "prod"}
-
-// loadKnownEnvs returns all known environments, including:
-// 1. Built-in defaults (dev, test, prod)
-// 2. Custom environments from ~/.cenvrc (one per line)
-// 3. Custom environments from $CENV_ENVIRONMENTS (space-separated)
-func loadKnownEnvs() []string {
-	envs := make([]string, len(defaultEnvs))
-	copy(envs, defaultEnvs)
-
-	// Load from ~/.cenvrc if it exists
-	if homeDir, err := os.UserHomeDir(); err == nil {
-		configPath := filepath.Join(homeDir, ".cenvrc")
-		if file, err := os.Open(configPath); err == nil {
-			defer file.Close()
-			scanner := bufio.NewScanner(file)
-			for scanner.Scan() {
-				line := strings.TrimSpace(scanner.Text())
-				// Skip empty lines and comments
-				if line == "" || strings.HasPrefix(line, "#") {
-					continue
-				}
-				// Remove inline comments
-				if idx := strings.Index(line, "#"); idx != -1 {
-					line = strings.TrimSpace(line[:idx])
-				}
-				if line != "" {
-					envs = append(envs, line)
-				}
-			}
-		}
-	}
+// Options controls how Resolve maps a path into another environment.
+type Options struct {
+	// Nearest makes Resolve fall back to the deepest existing ancestor inside
+	// the target environment when the exact counterpart does not exist.
+	Nearest bool
+	// RootMarkers lists the file or directory names that mark a repository
+	// root. When empty, $CENV_ROOT_MARKERS, the configured root_markers or
+	// ".git" is used.
+	RootMarkers []string
+	// ConfigPath overrides the user configuration file. When empty,
+	// $CENV_CONFIG or $XDG_CONFIG_HOME/changeenv/config.toml is used.
+	ConfigPath string
+	// Set assigns new values to layout dimensions, keyed by dimension name.
+	// An "env" entry is equivalent to passing the target environment.
+	Set map[string]string
+	// Occurrence overrides the configured policy for paths containing
+	// several environment segments. When empty, the configured occurrence or
+	// OccurrenceInnermostUnderRoot is used.
+	Occurrence Occurrence
+	// Config, when set, is used instead of loading the configuration for
+	// each path, so that callers mapping many paths load it only once.
+	// ConfigPath is ignored then.
+	Config *Config
+}
 
-	// Load from $CENV_ENVIRONMENTS if set
-	if cenvEnvs := os.Getenv("CENV_ENVIRONMENTS"); cenvEnvs != "" {
-		for _, env := range strings.Fields(cenvEnvs) {
-			env = strings.TrimSpace(env)
-			if env != "" {
-				envs = append(envs, env)
-			}
-		}
+// config returns opts.Config, or loads the configuration for dir.
+func (opts Options) config(dir string) (*Config, error) {
+	if opts.Config != nil {
+		return opts.Config, nil
 	}
+	return LoadConfig(dir, opts.ConfigPath)
+}
+
+// Result describes the outcome of resolving a path in another environment.
+type Result struct {
+	// Source is the cleaned input path.
+	Source string
+	// Target is the resolved path in the target environment.
+	Target string
+	// EnvFrom is the environment detected in Source.
+	EnvFrom string
+	// EnvTo is the canonical name of the target environment.
+	EnvTo string
+	// Root is the repository root the environment search was anchored to.
+	// It is empty when no root marker was found.
+	Root string
+	// Subpath is the part of Target below the target environment directory.
+	// It is empty when Target is the environment directory itself.
+	Subpath string
+	// Exists reports whether Target exists as a directory.
+	Exists bool
+	// Candidates lists every environment directory found in Source.
+	Candidates []Candidate
+	// Warnings describes surprising but successful resolutions, such as a
+	// path containing several environment segments under the default
+	// occurrence policy.
+	Warnings []string
+	// Rewrites lists the rewrite rules that changed the path below the
+	// environment directory, in the order they were applied.
+	Rewrites []Rewrite
+	// Dropped lists the trailing segments that were removed from the exact
+	// counterpart because it did not exist. It is only set when Nearest is
+	// enabled.
+	Dropped []string
+}
 
-	return envs
+// Exact reports whether Target is the exact counterpart of Source, i.e. no
+// trailing segments had to be dropped.
+func (r *Result) Exact() bool {
+	return len(r.Dropped) == 0
 }
 
 // Switch returns the equivalent path in the target environment.
 //
 // fromPath must point to a directory that sits under an environment directory
 // (for example ".../dev/..."). The first environment segment encountered in the
-// path is replaced with targetEnv. Known environment names are dev, test, and
-// prod, plus any custom environments from ~/.cenvrc or $CENV_ENVIRONMENTS.
+// path is replaced with targetEnv, unless an occurrence policy is configured.
+// When fromPath lies inside a repository, only segments below the repository
+// root are considered. Known environment names are dev, test, and prod, plus
+// any custom environments from ~/.cenvrc, the user config file,
+// $CENV_ENVIRONMENTS and .cenv or .cenv.toml files in fromPath or its
+// ancestors.
 func Switch(fromPath, targetEnv string) (string, error) {
+	result, err := Resolve(fromPath, targetEnv, Options{})
+	if err != nil {
+		return "", err
+	}
+	return result.Target, nil
+}
+
+// Resolve maps fromPath into targetEnv like Switch and reports additional
+// details about the resolved path.
+//
+// targetEnv may be an environment name, an alias, or a unique prefix of a
+// configured environment name. Path segments are matched against the
+// directory names of each environment, and the target environment's primary
+// directory name is written into the path. When a compound pattern is
+// configured and targetEnv matches only one of its components, just that
+// component of the current environment name is replaced.
+//
+// When a layout template matches fromPath, its {env} dimension determines the
+// environment segment and opts.Set may replace any other dimension. targetEnv
+// may then be empty if only other dimensions change.
+//
+// When the path contains several environment directories, opts.Occurrence or
+// the configured occurrence policy selects which one is switched. Under the
+// default policy a warning is added to the result.
+//
+// Configured rewrite rules for the environment pair are then applied to the
+// path below the environment directory.
+//
+// With opts.Nearest set, a missing counterpart is replaced by its deepest
+// existing ancestor below the first replaced segment. An error is returned if
+// that segment itself does not exist.
+func Resolve(fromPath, targetEnv string, opts Options) (*Result, error) {
+	return resolve(fromPath, targetEnv, opts, nil, nil)
+}
+
+// resolve implements Resolve, recording each step in tr when it is not nil.
+// The configuration for fromPath is loaded unless cfg is given.
+func resolve(fromPath, targetEnv string, opts Options, cfg *Config, tr *Trace) (*Result, error) {
 	targetEnv = strings.TrimSpace(targetEnv)
-	if targetEnv == "" {
-		return "", errors.New("target environment must not be empty")
+	if setEnv := strings.TrimSpace(opts.Set[EnvDimension]); setEnv != "" {
+		if targetEnv != "" && !strings.EqualFold(targetEnv, setEnv) {
+			return nil, fmt.Errorf("conflicting target environments %q and %s=%q", targetEnv, EnvDimension, setEnv)
+		}
+		targetEnv = setEnv
+	}
+	if targetEnv == "" && len(opts.Set) == 0 {
+		return nil, errors.New("target environment must not be empty")
 	}
 	if fromPath == "" {
-		return "", errors.New("current path must not be empty")
+		return nil, errors.New("current path must not be empty")
 	}
 
-	knownEnvs := loadKnownEnvs()
-
 	cleanFrom := filepath.Clean(fromPath)
-	volume, hasLeading, parts := splitPath(cleanFrom)
-	if len(parts) == 0 {
-		return "", fmt.Errorf("path %q is not inside a known environment", fromPath)
+	if tr != nil {
+		tr.Cleaned = cleanFrom
+	}
+	var err error
+	if cfg == nil {
+		if cfg, err = opts.config(cleanFrom); err != nil {
+			return nil, err
+		}
 	}
 
-	envIndex := -1
-	for idx, part := range parts {
-		if strings.EqualFold(part, targetEnv) || isKnownEnv(part, knownEnvs) {
-			envIndex = idx
-			break
+	// diskCased is set when the target directory should take the casing
+	// found on disk.
+	targetDir, diskCased := "", false
+	if targetEnv != "" {
+		if isPattern(targetEnv) {
+			return nil, fmt.Errorf("target environment %q must be a literal name, not a pattern", targetEnv)
 		}
+		var reason string
+		diskCased = cfg.foldTarget(targetEnv)
+		targetEnv, reason, err = cfg.canonicalEnv(targetEnv)
+		if err != nil {
+			return nil, err
+		}
+		targetDir = targetEnv
+		if env, ok := cfg.Lookup(targetEnv); ok {
+			targetDir = env.Dir()
+		}
+		if tr != nil {
+			tr.TargetEnv, tr.TargetReason, tr.TargetDir = targetEnv, reason, targetDir
+		}
+	}
+
+	policy, chosenPolicy, err := occurrencePolicy(opts.Occurrence, cfg)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := locate(cleanFrom, cfg, opts, policy, targetDir, tr)
+	if err != nil {
+		return nil, err
 	}
-	if envIndex == -1 {
-		return "", fmt.Errorf("path %q is not inside a known environment", fromPath)
+	parts := loc.parts
+	if targetEnv != "" && loc.envIndex == -1 {
+		return nil, loc.notInEnvError(fromPath)
 	}
 
-	parts[envIndex] = targetEnv
+	result := &Result{
+		Source:     cleanFrom,
+		EnvFrom:    loc.envFrom,
+		EnvTo:      loc.envFrom,
+		Root:       loc.root,
+		Candidates: loc.candidates,
+	}
+	if len(loc.candidates) > 1 && !chosenPolicy {
+		result.Warnings = append(result.Warnings, occurrenceWarning(loc.candidates, loc.candidates[policy.choose(loc.candidates)]))
+	}
 
-	targetPath := assemblePath(volume, hasLeading, parts)
+	// anchor is the outermost segment that must survive; Nearest never drops
+	// it or anything above it.
+	anchor := len(parts)
+	// envEnd is the index of the first segment below the environment
+	// directory, or -1 without one.
+	envEnd := -1
+	if loc.envIndex != -1 {
+		envEnd = loc.envIndex + loc.envLen
+	}
+	for _, name := range sortedKeys(opts.Set) {
+		if name == EnvDimension {
+			continue
+		}
+		if loc.layout == nil {
+			return nil, fmt.Errorf("cannot set %s: no layout matches %q", name, fromPath)
+		}
+		dim, ok := loc.dimension(name)
+		if !ok {
+			return nil, fmt.Errorf("cannot set %s: dimension is not present in %q (layout %q)", name, fromPath, loc.layout.Template)
+		}
+		value := strings.TrimSpace(opts.Set[name])
+		if value == "" || hasPathSeparator(value) {
+			return nil, fmt.Errorf("invalid value %q for dimension %s", value, name)
+		}
+		tr.step("set %s: %q -> %q at segment %d", name, parts[dim.Index], value, dim.Index)
+		parts[dim.Index] = value
+		anchor = min(anchor, dim.Index)
+	}
 
-	return targetPath, nil
-}
+	if targetEnv != "" {
+		chosen, _ := loc.chosen()
+		if cfg.Compound != nil && chosen.Marker == nil && loc.envLen == 1 {
+			parent := loc.path(parts[:loc.envIndex])
+			targetDir, err = cfg.composeTarget(parent, parts[loc.envIndex], targetEnv, targetDir, tr)
+			if err != nil {
+				return nil, err
+			}
+			targetEnv = cfg.envNameForDir(targetDir)
+		}
+		// The environment directory may span a different number of segments
+		// than the one it replaces, e.g. "dev" -> "prod/eu". A root declared
+		// by a marker file is replaced by the sibling whose marker declares
+		// the target environment.
+		targetSegments := dirSegments(targetDir)
+		chosenLen := len(targetSegments)
+		shift := 0
+		for _, candidate := range loc.replaced() {
+			index := candidate.Index + shift
+			segments := targetSegments
+			if candidate.Marker != nil {
+				sibling, err := cfg.markerSibling(loc.path(parts[:index]), targetEnv, tr)
+				if err != nil {
+					return nil, err
+				}
+				segments = []string{sibling}
+			} else {
+				if diskCased {
+					segments = diskCase(loc.path(parts[:index]), segments)
+				}
+				if err := cfg.checkTarget(targetEnv, loc.path(parts[:index]), segments, tr); err != nil {
+					return nil, err
+				}
+			}
+			if candidate.Index == loc.envIndex {
+				chosenLen = len(segments)
+			}
+			tr.step("replaced %q at segment %d with %q", candidate.Dir, candidate.Index, strings.Join(segments, "/"))
+			parts = slices.Concat(parts[:index], segments, parts[index+candidate.Len:])
+			shift += len(segments) - candidate.Len
+		}
+		lastEnvSegment := loc.envIndex + chosenLen - 1
+		if anchor > loc.envIndex && anchor < len(loc.parts) {
+			anchor += chosenLen - loc.envLen
+		}
+		anchor = min(anchor, lastEnvSegment)
+		envEnd = lastEnvSegment + 1
+		result.EnvTo = targetEnv
+
+		if len(cfg.Rules) > 0 {
+			subpath := strings.Join(parts[lastEnvSegment+1:], "/")
+			rewritten, applied, err := cfg.applyRewrites(result.EnvFrom, result.EnvTo, subpath)
+			if err != nil {
+				return nil, err
+			}
+			if len(applied) > 0 {
+				parts = parts[:lastEnvSegment+1]
+				if rewritten != "" {
+					parts = append(parts, strings.Split(rewritten, "/")...)
+				}
+				result.Rewrites = applied
+				if tr != nil {
+					tr.Rewrites = applied
+				}
+			}
+		}
+	}
 
-func isKnownEnv(name string, knownEnvs []string) bool {
-	for _, env := range knownEnvs {
-		if strings.EqualFold(env, name) {
-			return true
+	result.Target = loc.path(parts)
+	if envEnd != -1 {
+		result.Subpath = filepath.Join(parts[envEnd:]...)
+	}
+	if tr != nil {
+		tr.Assembled = result.Target
+	}
+	result.Exists = tr.exists(result.Target)
+	if result.Exists || !opts.Nearest {
+		return result, nil
+	}
+
+	for end := len(parts) - 1; end > anchor; end-- {
+		ancestor := loc.path(parts[:end])
+		if tr.exists(ancestor) {
+			tr.step("fell back to %q, dropping %q", ancestor, strings.Join(parts[end:], "/"))
+			result.Target = ancestor
+			if envEnd != -1 {
+				result.Subpath = filepath.Join(parts[envEnd:max(end, envEnd)]...)
+			}
+			result.Exists = true
+			result.Dropped = append([]string(nil), parts[end:]...)
+			return result, nil
 		}
 	}
-	return false
+
+	return nil, &TargetMissingError{Path: loc.path(parts[:anchor+1])}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
 }
 
 func splitPath(path string) (volume string, hasLeading bool, parts []string) {
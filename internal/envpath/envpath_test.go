@@ -3,12 +3,14 @@ package envpath_test
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"envchanger/internal/envpath"
 )
 
 func TestSwitchReturnsTargetPath(t *testing.T) {
+	envpath.IsolateConfig(t)
 	root := t.TempDir()
 	devPath := filepath.Join(root, "dev", "eu-west6", "infra")
 	prodPath := filepath.Join(root, "prod", "eu-west6", "infra")
@@ -27,6 +29,7 @@ func TestSwitchReturnsTargetPath(t *testing.T) {
 }
 
 func TestSwitchValidatesEnvironmentSegment(t *testing.T) {
+	envpath.IsolateConfig(t)
 	root := t.TempDir()
 	path := filepath.Join(root, "misc", "eu-west6")
 	mustMkdirAll(t, path)
@@ -38,6 +41,7 @@ func TestSwitchValidatesEnvironmentSegment(t *testing.T) {
 }
 
 func TestSwitchReturnsTargetEvenIfItDoesNotExist(t *testing.T) {
+	envpath.IsolateConfig(t)
 	root := t.TempDir()
 	devPath := filepath.Join(root, "dev", "cluster")
 	mustMkdirAll(t, devPath)
@@ -53,6 +57,7 @@ func TestSwitchReturnsTargetEvenIfItDoesNotExist(t *testing.T) {
 }
 
 func TestSwitchWithCustomEnvironmentFromEnvVar(t *testing.T) {
+	envpath.IsolateConfig(t)
 	// Set custom environment via environment variable
 	t.Setenv("CENV_ENVIRONMENTS", "prod-us-east-1 test-eu-central-1")
 
@@ -73,8 +78,7 @@ func TestSwitchWithCustomEnvironmentFromEnvVar(t *testing.T) {
 
 func TestSwitchWithCustomEnvironmentFromConfigFile(t *testing.T) {
 	// Create a temporary home directory
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
+	tmpHome := envpath.IsolateConfig(t)
 
 	// Create .cenvrc with custom environments
 	cenvrcPath := filepath.Join(tmpHome, ".cenvrc")
@@ -104,8 +108,7 @@ staging
 
 func TestSwitchWithBothConfigFileAndEnvVar(t *testing.T) {
 	// Create a temporary home directory
-	tmpHome := t.TempDir()
-	t.Setenv("HOME", tmpHome)
+	tmpHome := envpath.IsolateConfig(t)
 
 	// Create .cenvrc
 	cenvrcPath := filepath.Join(tmpHome, ".cenvrc")
@@ -132,9 +135,226 @@ func TestSwitchWithBothConfigFileAndEnvVar(t *testing.T) {
 	}
 }
 
+func TestResolveReportsExactMatch(t *testing.T) {
+	envpath.IsolateConfig(t)
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "services", "app")
+	prodPath := filepath.Join(root, "prod", "services", "app")
+	mustMkdirAll(t, devPath)
+	mustMkdirAll(t, prodPath)
+
+	result, err := envpath.Resolve(devPath, "prod", envpath.Options{Nearest: true})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if result.Target != prodPath || !result.Exists || !result.Exact() {
+		t.Fatalf("expected exact match %q, got %+v", prodPath, result)
+	}
+}
+
+func TestResolveNearestFallsBackToExistingAncestor(t *testing.T) {
+	envpath.IsolateConfig(t)
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "services", "new-app", "config")
+	prodServices := filepath.Join(root, "prod", "services")
+	mustMkdirAll(t, devPath)
+	mustMkdirAll(t, prodServices)
+
+	result, err := envpath.Resolve(devPath, "prod", envpath.Options{Nearest: true})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if result.Target != prodServices {
+		t.Fatalf("expected target %q, got %q", prodServices, result.Target)
+	}
+	if result.Exact() {
+		t.Fatalf("expected approximate match")
+	}
+	if got := strings.Join(result.Dropped, "/"); got != "new-app/config" {
+		t.Fatalf("expected dropped segments %q, got %q", "new-app/config", got)
+	}
+}
+
+func TestResolveNearestFailsWhenEnvironmentMissing(t *testing.T) {
+	envpath.IsolateConfig(t)
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "services")
+	mustMkdirAll(t, devPath)
+
+	if _, err := envpath.Resolve(devPath, "prod", envpath.Options{Nearest: true}); err == nil {
+		t.Fatalf("expected error when the target environment directory is missing")
+	}
+}
+
+func TestResolveWithoutNearestKeepsMissingTarget(t *testing.T) {
+	envpath.IsolateConfig(t)
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "services")
+	mustMkdirAll(t, devPath)
+
+	result, err := envpath.Resolve(devPath, "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	expected := filepath.Join(root, "prod", "services")
+	if result.Target != expected || result.Exists {
+		t.Fatalf("expected missing target %q, got %+v", expected, result)
+	}
+}
+
+func TestSwitchIgnoresSegmentsAboveRepositoryRoot(t *testing.T) {
+	envpath.IsolateConfig(t)
+	base := t.TempDir()
+	repo := filepath.Join(base, "dev", "infra")
+	testPath := filepath.Join(repo, "test", "services")
+	mustMkdirAll(t, filepath.Join(repo, ".git"))
+	mustMkdirAll(t, testPath)
+
+	result, err := envpath.Resolve(testPath, "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	expected := filepath.Join(repo, "prod", "services")
+	if result.Target != expected {
+		t.Fatalf("expected target %q, got %q", expected, result.Target)
+	}
+	if result.Root != repo {
+		t.Fatalf("expected root %q, got %q", repo, result.Root)
+	}
+}
+
+func TestSwitchFailsWhenOnlySegmentsAboveRootMatch(t *testing.T) {
+	envpath.IsolateConfig(t)
+	base := t.TempDir()
+	repo := filepath.Join(base, "prod", "infra")
+	path := filepath.Join(repo, "services")
+	mustMkdirAll(t, filepath.Join(repo, ".git"))
+	mustMkdirAll(t, path)
+
+	if _, err := envpath.Switch(path, "dev"); err == nil {
+		t.Fatalf("expected error when the only environment segment is above the root")
+	}
+}
+
+func TestResolveUsesCustomRootMarker(t *testing.T) {
+	envpath.IsolateConfig(t)
+	base := t.TempDir()
+	repo := filepath.Join(base, "test", "checkout")
+	devPath := filepath.Join(repo, "dev", "app")
+	mustMkdirAll(t, devPath)
+	if err := os.WriteFile(filepath.Join(repo, ".cenv-root"), nil, 0o644); err != nil {
+		t.Fatalf("write marker: %v", err)
+	}
+
+	result, err := envpath.Resolve(devPath, "prod", envpath.Options{RootMarkers: []string{".cenv-root"}})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	expected := filepath.Join(repo, "prod", "app")
+	if result.Target != expected {
+		t.Fatalf("expected target %q, got %q", expected, result.Target)
+	}
+}
+
+func TestFindRootReportsMissingMarker(t *testing.T) {
+	envpath.IsolateConfig(t)
+	dir := t.TempDir()
+	if root, ok := envpath.FindRoot(dir, []string{"no-such-marker-file"}); ok {
+		t.Fatalf("expected no root, got %q", root)
+	}
+}
+
+func TestSwitchWithRepositoryConfig(t *testing.T) {
+	envpath.IsolateConfig(t)
+
+	repo := t.TempDir()
+	envpath.WriteFile(t, filepath.Join(repo, ".cenv"), "# repo environments\nstaging\ncanary\n")
+
+	stagingPath := filepath.Join(repo, "staging", "services")
+	mustMkdirAll(t, stagingPath)
+
+	target, err := envpath.Switch(stagingPath, "canary")
+	if err != nil {
+		t.Fatalf("Switch returned error: %v", err)
+	}
+	expected := filepath.Join(repo, "canary", "services")
+	if target != expected {
+		t.Fatalf("expected target %q, got %q", expected, target)
+	}
+}
+
+func TestNestedRepositoryConfigOverridesParent(t *testing.T) {
+	envpath.IsolateConfig(t)
+
+	repo := t.TempDir()
+	envpath.WriteFile(t, filepath.Join(repo, ".cenv"), "staging\n")
+	subRepo := filepath.Join(repo, "modules", "network")
+	mustMkdirAll(t, subRepo)
+	envpath.WriteFile(t, filepath.Join(subRepo, ".cenv"), "!staging\nqa\n")
+
+	stagingPath := filepath.Join(subRepo, "staging", "qa", "vpc")
+	mustMkdirAll(t, stagingPath)
+
+	target, err := envpath.Switch(stagingPath, "prod")
+	if err != nil {
+		t.Fatalf("Switch returned error: %v", err)
+	}
+	expected := filepath.Join(subRepo, "staging", "prod", "vpc")
+	if target != expected {
+		t.Fatalf("expected nested config to drop staging; got %q, want %q", target, expected)
+	}
+}
+
+// setupRepo isolates the configuration and returns a new repository root
+// whose .cenv.toml, unless config is empty, holds config. Each of paths is
+// created below the root, as a directory when it ends in a slash and as an
+// empty file otherwise.
+func setupRepo(t *testing.T, config string, paths ...string) string {
+	t.Helper()
+	envpath.IsolateConfig(t)
+	repo := t.TempDir()
+	mustMkdirAll(t, filepath.Join(repo, ".git"))
+	if config != "" {
+		envpath.WriteFile(t, filepath.Join(repo, ".cenv.toml"), config)
+	}
+	for _, path := range paths {
+		if strings.HasSuffix(path, "/") {
+			mustMkdirAll(t, filepath.Join(repo, path))
+		} else {
+			envpath.WriteFile(t, filepath.Join(repo, path), "")
+		}
+	}
+	return repo
+}
+
 func mustMkdirAll(t *testing.T, path string) {
 	t.Helper()
 	if err := os.MkdirAll(path, 0o755); err != nil {
 		t.Fatalf("failed to create %q: %v", path, err)
 	}
 }
+
+func TestResolveUsesPreloadedConfig(t *testing.T) {
+	root := setupRepo(t, "", "qa/app/", "uat/app/")
+
+	cfg, err := envpath.LoadConfig(root, "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if _, err := envpath.Resolve(filepath.Join(root, "qa", "app"), "uat", envpath.Options{Config: cfg}); err == nil {
+		t.Fatalf("expected qa to be unknown to the preloaded config")
+	}
+
+	// A config file written after loading is not consulted.
+	envpath.WriteFile(t, filepath.Join(root, ".cenv"), "qa\nuat\n")
+	if _, err := envpath.Resolve(filepath.Join(root, "qa", "app"), "uat", envpath.Options{Config: cfg}); err == nil {
+		t.Fatalf("expected the preloaded config to be used instead of reloading")
+	}
+	result, err := envpath.Resolve(filepath.Join(root, "qa", "app"), "uat", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(root, "uat", "app"); result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+}
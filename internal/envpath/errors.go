@@ -0,0 +1,234 @@
+package envpath
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Sentinel errors matched by the error types below, for use with errors.Is.
+//
+// The types are returned, possibly wrapped, by the functions that resolve a
+// path into a target environment: Resolve, Switch, ResolveFile, SwitchFile,
+// Explain and CounterpartFiles. Each type lists any other sources.
+var (
+	// ErrNotInEnv reports a path outside every known environment.
+	ErrNotInEnv = errors.New("not inside a known environment")
+	// ErrUnknownTarget reports a target that names no environment.
+	ErrUnknownTarget = errors.New("unknown target environment")
+	// ErrAmbiguousTarget reports a target that could name several
+	// environments.
+	ErrAmbiguousTarget = errors.New("ambiguous target environment")
+	// ErrAmbiguousSegment reports a path segment that could name several
+	// environments.
+	ErrAmbiguousSegment = errors.New("ambiguous environment segment")
+	// ErrTargetMissing reports a counterpart that does not exist where it
+	// must.
+	ErrTargetMissing = errors.New("target does not exist")
+)
+
+// NotInEnvError is returned when a path does not lie inside a known
+// environment. Counterparts, BuildMatrix and Drift return it for their path
+// too.
+type NotInEnvError struct {
+	Path string
+	// Root is the repository root below which environments were searched,
+	// or empty when the whole path was searched.
+	Root string
+}
+
+func (e *NotInEnvError) Error() string {
+	if e.Root != "" {
+		return fmt.Sprintf("path %q is not inside a known environment below repository root %q", e.Path, e.Root)
+	}
+	return fmt.Sprintf("path %q is not inside a known environment", e.Path)
+}
+
+// Is reports whether target is ErrNotInEnv.
+func (e *NotInEnvError) Is(target error) bool { return target == ErrNotInEnv }
+
+// UnknownTargetError is returned when no directory can be found for the
+// target environment and it is not configured, but a known environment or
+// directory has a similar name. Unknown targets without a similar name are
+// used verbatim.
+type UnknownTargetError struct {
+	Target string
+	// Dir is the directory the environment was looked for in.
+	Dir string
+	// Marker is the marker file name when the environment was looked for by
+	// marker, as for roots declared by marker files.
+	Marker string
+	// Suggestions lists known environments and directories with similar
+	// names, closest first.
+	Suggestions []string
+}
+
+func (e *UnknownTargetError) Error() string {
+	if e.Marker != "" {
+		return fmt.Sprintf("no directory in %q has a %s marker declaring %q", e.Dir, e.Marker, e.Target)
+	}
+	return fmt.Sprintf("unknown target environment %q: it is not configured and %q has no such directory", e.Target, e.Dir)
+}
+
+// Is reports whether target is ErrUnknownTarget.
+func (e *UnknownTargetError) Is(target error) bool { return target == ErrUnknownTarget }
+
+// AmbiguousTargetError is returned when a target environment prefix matches
+// more than one known environment. Config.CanonicalEnv returns it too.
+type AmbiguousTargetError struct {
+	Target     string
+	Candidates []string
+}
+
+func (e *AmbiguousTargetError) Error() string {
+	return fmt.Sprintf("target environment %q is ambiguous: could be %s", e.Target, strings.Join(e.Candidates, ", "))
+}
+
+// Is reports whether target is ErrAmbiguousTarget.
+func (e *AmbiguousTargetError) Is(target error) bool { return target == ErrAmbiguousTarget }
+
+// AmbiguousSegmentError is returned when a segment of a path could be one of
+// several names. ResolveFile and SwitchFile return it for a file name naming
+// several environments, so the current environment cannot be told. Every
+// resolving function returns it when several directories next to a
+// marker-declared environment root declare the target environment, so the
+// target directory cannot be told; Segment is then the target and Candidates
+// are the directories.
+type AmbiguousSegmentError struct {
+	Path       string
+	Segment    string
+	Candidates []string
+	// Marker is the marker file name when the candidates are directories
+	// declaring Segment by marker.
+	Marker string
+}
+
+func (e *AmbiguousSegmentError) Error() string {
+	if e.Marker != "" {
+		return fmt.Sprintf("several directories in %q have a %s marker declaring %q: %s", e.Path, e.Marker, e.Segment, strings.Join(e.Candidates, ", "))
+	}
+	return fmt.Sprintf("segment %q of %q names several environments: %s", e.Segment, e.Path, strings.Join(e.Candidates, ", "))
+}
+
+// Is reports whether target is ErrAmbiguousSegment.
+func (e *AmbiguousSegmentError) Is(target error) bool { return target == ErrAmbiguousSegment }
+
+// TargetMissingError is returned when a directory the resolution depends on
+// does not exist, for example the target environment directory under
+// Options.Nearest.
+type TargetMissingError struct {
+	Path string
+	// Alternatives lists other directories that were tried instead.
+	Alternatives []string
+}
+
+func (e *TargetMissingError) Error() string {
+	msg := fmt.Sprintf("directory %q does not exist", e.Path)
+	for _, alternative := range e.Alternatives {
+		msg += fmt.Sprintf(", and neither does %q", alternative)
+	}
+	return msg
+}
+
+// Is reports whether target is ErrTargetMissing.
+func (e *TargetMissingError) Is(target error) bool { return target == ErrTargetMissing }
+
+// checkTarget reports an UnknownTargetError when target, to be placed at
+// segments below parent, looks like a misspelt environment: it is neither
+// configured nor matched by a pattern, its directory does not exist, and a
+// known environment or a directory in parent has a similar name. Other
+// unknown targets are kept verbatim.
+func (c *Config) checkTarget(target, parent string, segments []string, tr *Trace) error {
+	if _, ok := c.Lookup(target); ok {
+		return nil
+	}
+	if _, ok := c.matchPattern(target); ok {
+		return nil
+	}
+	if tr.exists(filepath.Join(parent, filepath.Join(segments...))) {
+		return nil
+	}
+	if err := c.unknownTarget(target, parent); len(err.Suggestions) > 0 {
+		return err
+	}
+	return nil
+}
+
+// unknownTarget returns an UnknownTargetError for target in dir, suggesting
+// configured environments, their aliases and the directories in dir with
+// similar names.
+func (c *Config) unknownTarget(target, dir string) *UnknownTargetError {
+	var names []string
+	for _, env := range c.Envs {
+		names = append(names, env.Name)
+		for _, alias := range env.Aliases {
+			names = append(names, alias.Name)
+		}
+	}
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	return &UnknownTargetError{Target: target, Dir: dir, Suggestions: suggest(target, names)}
+}
+
+// suggest returns the names within a small edit distance of target, closest
+// first, without duplicates.
+func suggest(target string, names []string) []string {
+	type scored struct {
+		name     string
+		distance int
+	}
+	limit := max(1, min(2, len(target)/3))
+	var matches []scored
+	seen := make(map[string]bool)
+	for _, name := range names {
+		key := strings.ToLower(name)
+		if seen[key] || strings.EqualFold(name, target) {
+			continue
+		}
+		seen[key] = true
+		if distance := editDistance(strings.ToLower(target), key); distance <= limit {
+			matches = append(matches, scored{name, distance})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b scored) int { return a.distance - b.distance })
+	suggestions := make([]string, len(matches))
+	for i, match := range matches {
+		suggestions[i] = match.name
+	}
+	return suggestions
+}
+
+// editDistance returns the Damerau-Levenshtein distance between a and b,
+// counting adjacent transpositions as one edit.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
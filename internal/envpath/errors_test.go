@@ -0,0 +1,106 @@
+package envpath_test
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestSwitchReportsNotInEnvError(t *testing.T) {
+	envpath.IsolateConfig(t)
+
+	root := t.TempDir()
+	path := filepath.Join(root, "src", "app")
+	mustMkdirAll(t, path)
+
+	_, err := envpath.Switch(path, "prod")
+	if !errors.Is(err, envpath.ErrNotInEnv) {
+		t.Fatalf("expected ErrNotInEnv, got %v", err)
+	}
+	var notInEnv *envpath.NotInEnvError
+	if !errors.As(err, &notInEnv) || notInEnv.Path != path {
+		t.Fatalf("expected NotInEnvError for %q, got %#v", path, err)
+	}
+}
+
+func TestSwitchReportsMisspeltTarget(t *testing.T) {
+	envpath.IsolateConfig(t)
+
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "app")
+	mustMkdirAll(t, devPath)
+	mustMkdirAll(t, filepath.Join(root, "prod"))
+
+	_, err := envpath.Switch(devPath, "prdo")
+	if !errors.Is(err, envpath.ErrUnknownTarget) {
+		t.Fatalf("expected ErrUnknownTarget, got %v", err)
+	}
+	var unknown *envpath.UnknownTargetError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("expected UnknownTargetError, got %v", err)
+	}
+	if unknown.Target != "prdo" || unknown.Dir != root || !slices.Equal(unknown.Suggestions, []string{"prod"}) {
+		t.Fatalf("unexpected error details: %+v", unknown)
+	}
+
+	// A target whose directory exists is used even when unconfigured.
+	mustMkdirAll(t, filepath.Join(root, "prdo"))
+	if _, err := envpath.Switch(devPath, "prdo"); err != nil {
+		t.Fatalf("Switch returned error: %v", err)
+	}
+}
+
+func TestResolveNearestReportsTargetMissing(t *testing.T) {
+	envpath.IsolateConfig(t)
+
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "app")
+	mustMkdirAll(t, devPath)
+
+	_, err := envpath.Resolve(devPath, "prod", envpath.Options{Nearest: true})
+	if !errors.Is(err, envpath.ErrTargetMissing) {
+		t.Fatalf("expected ErrTargetMissing, got %v", err)
+	}
+	var missing *envpath.TargetMissingError
+	if !errors.As(err, &missing) || missing.Path != filepath.Join(root, "prod") {
+		t.Fatalf("expected TargetMissingError for the prod directory, got %#v", err)
+	}
+}
+
+func TestAmbiguousTargetMatchesSentinel(t *testing.T) {
+	envpath.IsolateConfig(t)
+	t.Setenv("CENV_ENVIRONMENTS", "preview")
+
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "app")
+	mustMkdirAll(t, devPath)
+
+	_, err := envpath.Switch(devPath, "pr")
+	if !errors.Is(err, envpath.ErrAmbiguousTarget) {
+		t.Fatalf("expected ErrAmbiguousTarget, got %v", err)
+	}
+	if errors.Is(err, envpath.ErrUnknownTarget) {
+		t.Fatalf("ambiguous target also matched ErrUnknownTarget")
+	}
+}
+
+func TestResolveReportsSeveralMarkerSiblingsAsAmbiguousSegment(t *testing.T) {
+	repo := setupRepo(t, "", "accounts/333333333333/")
+	writeAccountMarkers(t, repo)
+	envpath.WriteFile(t, filepath.Join(repo, "accounts", "333333333333", ".cenv-env"), "env = \"prod\"\n")
+
+	_, err := envpath.Resolve(filepath.Join(repo, "accounts", "111111111111", "network"), "prod", envpath.Options{})
+	if !errors.Is(err, envpath.ErrAmbiguousSegment) || errors.Is(err, envpath.ErrAmbiguousTarget) {
+		t.Fatalf("expected only ErrAmbiguousSegment, got %v", err)
+	}
+	var ambiguous *envpath.AmbiguousSegmentError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected AmbiguousSegmentError, got %v", err)
+	}
+	if ambiguous.Segment != "prod" || ambiguous.Path != filepath.Join(repo, "accounts") || !slices.Equal(ambiguous.Candidates, []string{"222222222222", "333333333333"}) {
+		t.Fatalf("unexpected error details: %+v", ambiguous)
+	}
+}
@@ -0,0 +1,7 @@
+package envpath
+
+// Test helpers shared with the black-box tests in package envpath_test.
+var (
+	IsolateConfig = isolateConfig
+	WriteFile     = writeFile
+)
@@ -0,0 +1,143 @@
+package envpath
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SwitchFile returns the equivalent of a file or directory path in the target
+// environment. It behaves like Switch, except that fromPath may name a file,
+// which need not exist, and environment tokens in the file name are rewritten
+// too, so "dev/app/values-dev.yaml" becomes "prod/app/values-prod.yaml".
+func SwitchFile(fromPath, targetEnv string) (string, error) {
+	result, err := ResolveFile(fromPath, targetEnv, Options{})
+	if err != nil {
+		return "", err
+	}
+	return result.Target, nil
+}
+
+// ResolveFile maps fromPath into targetEnv like SwitchFile and reports
+// additional details about the resolved path.
+//
+// Directories are resolved like Resolve. For anything else, the directory
+// holding it is resolved and every token of the file name naming the current
+// environment or one of its directories is replaced by the target
+// environment's name or directory. Tokens must be delimited by the ends of
+// the name or by the configured token_boundaries, "-_." by default; a token
+// written in upper case is replaced in upper case.
+//
+// When the directory does not lie inside an environment, the environment is
+// taken from the file name instead, so "terraform/dev.tfvars" becomes
+// "terraform/prod.tfvars". A file name naming several known environments
+// yields an *AmbiguousSegmentError.
+func ResolveFile(fromPath, targetEnv string, opts Options) (*Result, error) {
+	if fromPath == "" {
+		return nil, errors.New("path must not be empty")
+	}
+	cleanPath := filepath.Clean(fromPath)
+	if isDir(cleanPath) {
+		return Resolve(cleanPath, targetEnv, opts)
+	}
+	dir, base := filepath.Dir(cleanPath), filepath.Base(cleanPath)
+	cfg, err := opts.config(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, _, err := occurrencePolicy(opts.Occurrence, cfg)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := locate(dir, cfg, opts, policy, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *Result
+	if loc.envIndex == -1 {
+		envs := cfg.fileNameEnvs(base)
+		switch len(envs) {
+		case 0:
+			return nil, loc.notInEnvError(cleanPath)
+		case 1:
+		default:
+			return nil, &AmbiguousSegmentError{Path: cleanPath, Segment: base, Candidates: envs}
+		}
+		envFrom := envs[0]
+		envTo, err := cfg.CanonicalEnv(targetEnv)
+		if err != nil {
+			return nil, err
+		}
+		result = &Result{Target: dir, EnvFrom: envFrom, EnvTo: envTo, Root: loc.root}
+	} else {
+		result, err = resolve(dir, targetEnv, opts, cfg, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	name := cfg.switchFileName(base, result.EnvFrom, result.EnvTo)
+	result.Source = cleanPath
+	result.Target = filepath.Join(result.Target, name)
+	if loc.envIndex != -1 {
+		result.Subpath = filepath.Join(result.Subpath, name)
+	}
+	_, statErr := os.Stat(result.Target)
+	result.Exists = statErr == nil
+	return result, nil
+}
+
+// fileNameEnvs returns the configured environments named by tokens of base,
+// in the order they appear. Where tokens of several environments overlap, the
+// longest wins.
+func (c *Config) fileNameEnvs(base string) []string {
+	owners := make(map[string]string)
+	var tokens []string
+	for _, env := range c.Envs {
+		for _, token := range c.EnvTokens(env.Name) {
+			owners[strings.ToLower(token)] = env.Name
+			tokens = append(tokens, token)
+		}
+	}
+	var envs []string
+	c.replaceTokens(base, tokens, c.tokenBoundaries(), func(match string) string {
+		if env := owners[strings.ToLower(match)]; !slices.Contains(envs, env) {
+			envs = append(envs, env)
+		}
+		return match
+	})
+	return envs
+}
+
+// switchFileName rewrites the tokens of base naming envFrom to name envTo.
+// A token matching one of envFrom's directory names becomes envTo's
+// directory; any other token becomes envTo's name.
+func (c *Config) switchFileName(base, envFrom, envTo string) string {
+	if envFrom == "" || envTo == "" || c.sameSegment(envFrom, envTo) {
+		return base
+	}
+	fromDirs := []string{envFrom}
+	toName, toDir := envTo, envTo
+	if e, ok := c.Lookup(envFrom); ok {
+		fromDirs = e.DirNames()
+	}
+	if e, ok := c.Lookup(envTo); ok {
+		toName, toDir = e.Name, e.Dir()
+	}
+	return c.replaceTokens(base, c.EnvTokens(envFrom), c.tokenBoundaries(), func(match string) string {
+		replacement := toName
+		for _, dir := range fromDirs {
+			if c.sameSegment(match, dir) && !c.sameSegment(match, envFrom) {
+				replacement = toDir
+			}
+		}
+		if match == strings.ToUpper(match) && match != strings.ToLower(match) {
+			return strings.ToUpper(replacement)
+		}
+		return replacement
+	})
+}
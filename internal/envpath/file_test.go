@@ -0,0 +1,137 @@
+package envpath_test
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+// fileDirs are the directories of the repository the file tests switch in.
+var fileDirs = []string{"dev/app/", "prod/app/", "terraform/"}
+
+func TestResolveFileRewritesFileNameTokens(t *testing.T) {
+	repo := setupRepo(t, "", fileDirs...)
+	envpath.WriteFile(t, filepath.Join(repo, "prod", "app", "values-prod.yaml"), "")
+
+	tests := []struct {
+		from, want string
+	}{
+		{"values-dev.yaml", "values-prod.yaml"},
+		{"dev.tfvars", "prod.tfvars"},
+		{"DEV_NOTES.md", "PROD_NOTES.md"},
+		{"device.yaml", "device.yaml"},
+		{"values.dev-dev.yaml", "values.prod-prod.yaml"},
+	}
+	for _, tt := range tests {
+		result, err := envpath.ResolveFile(filepath.Join(repo, "dev", "app", tt.from), "prod", envpath.Options{})
+		if err != nil {
+			t.Fatalf("%s: ResolveFile returned error: %v", tt.from, err)
+		}
+		if expected := filepath.Join(repo, "prod", "app", tt.want); result.Target != expected {
+			t.Fatalf("%s: expected %q, got %q", tt.from, expected, result.Target)
+		}
+	}
+
+	target, err := envpath.SwitchFile(filepath.Join(repo, "dev", "app", "values-dev.yaml"), "prod")
+	if err != nil {
+		t.Fatalf("SwitchFile returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "prod", "app", "values-prod.yaml"); target != expected {
+		t.Fatalf("expected %q, got %q", expected, target)
+	}
+}
+
+func TestResolveFileReportsExistence(t *testing.T) {
+	repo := setupRepo(t, "", fileDirs...)
+	envpath.WriteFile(t, filepath.Join(repo, "prod", "app", "values-prod.yaml"), "")
+
+	result, err := envpath.ResolveFile(filepath.Join(repo, "dev", "app", "values-dev.yaml"), "prod", envpath.Options{})
+	if err != nil || !result.Exists {
+		t.Fatalf("expected an existing counterpart, got %+v, %v", result, err)
+	}
+	result, err = envpath.ResolveFile(filepath.Join(repo, "dev", "app", "other.yaml"), "prod", envpath.Options{})
+	if err != nil || result.Exists {
+		t.Fatalf("expected a missing counterpart, got %+v, %v", result, err)
+	}
+}
+
+func TestResolveFileUsesDirectoryNames(t *testing.T) {
+	repo := setupRepo(t, `[env.prod]
+dirs = ["production"]
+`, fileDirs...)
+	mustMkdirAll(t, filepath.Join(repo, "production", "app"))
+
+	target, err := envpath.SwitchFile(filepath.Join(repo, "dev", "app", "values-dev.yaml"), "prod")
+	if err != nil {
+		t.Fatalf("SwitchFile returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "production", "app", "values-prod.yaml"); target != expected {
+		t.Fatalf("expected %q, got %q", expected, target)
+	}
+
+	target, err = envpath.SwitchFile(filepath.Join(repo, "production", "app", "production.env"), "dev")
+	if err != nil {
+		t.Fatalf("SwitchFile returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "dev", "app", "dev.env"); target != expected {
+		t.Fatalf("expected %q, got %q", expected, target)
+	}
+}
+
+func TestResolveFileTakesEnvFromFileName(t *testing.T) {
+	repo := setupRepo(t, "", fileDirs...)
+
+	result, err := envpath.ResolveFile(filepath.Join(repo, "terraform", "dev.tfvars"), "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("ResolveFile returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "terraform", "prod.tfvars"); result.Target != expected || result.EnvFrom != "dev" {
+		t.Fatalf("expected %q from dev, got %+v", expected, result)
+	}
+
+	if _, err := envpath.ResolveFile(filepath.Join(repo, "terraform", "main.tf"), "prod", envpath.Options{}); !errors.Is(err, envpath.ErrNotInEnv) {
+		t.Fatalf("main.tf: expected a not-in-environment error, got %v", err)
+	}
+
+	_, err = envpath.ResolveFile(filepath.Join(repo, "terraform", "dev-to-prod.tfvars"), "prod", envpath.Options{})
+	var ambiguous *envpath.AmbiguousSegmentError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("dev-to-prod.tfvars: expected an ambiguous segment error, got %v", err)
+	}
+	if ambiguous.Segment != "dev-to-prod.tfvars" || !slices.Equal(ambiguous.Candidates, []string{"dev", "prod"}) {
+		t.Fatalf("unexpected error details: %+v", ambiguous)
+	}
+}
+
+func TestResolveFileHonoursTokenBoundaries(t *testing.T) {
+	repo := setupRepo(t, "token_boundaries = \"-.\"\n", fileDirs...)
+
+	tests := []struct {
+		from, want string
+	}{
+		{"values-dev.yaml", "values-prod.yaml"},
+		{"values_dev.yaml", "values_dev.yaml"},
+	}
+	for _, tt := range tests {
+		target, err := envpath.SwitchFile(filepath.Join(repo, "dev", "app", tt.from), "prod")
+		if err != nil {
+			t.Fatalf("%s: SwitchFile returned error: %v", tt.from, err)
+		}
+		if expected := filepath.Join(repo, "prod", "app", tt.want); target != expected {
+			t.Fatalf("%s: expected %q, got %q", tt.from, expected, target)
+		}
+	}
+}
+
+func TestResolveFileRejectsInvalidTokenBoundaries(t *testing.T) {
+	repo := setupRepo(t, "token_boundaries = \"a-\"\n", fileDirs...)
+
+	_, err := envpath.ResolveFile(filepath.Join(repo, "dev", "app", "values-dev.yaml"), "prod", envpath.Options{})
+	if err == nil || !strings.Contains(err.Error(), "token_boundaries must be") {
+		t.Fatalf("expected a token_boundaries error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package envpath
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreMarkerName is a file that excludes the directory containing it, and
+// everything below, from environment detection. Its contents are not read.
+const ignoreMarkerName = ".cenvignore"
+
+// IgnoreRule excludes directories from environment detection. Rules use
+// gitignore syntax relative to the repository root: a pattern without a slash
+// matches a segment name at any depth, a pattern containing a slash is
+// anchored to the root, "**" matches any number of segments and a leading "!"
+// re-includes what an earlier rule excluded. A matching directory and its
+// whole subtree are skipped.
+type IgnoreRule struct {
+	Raw    string
+	Origin Origin
+
+	negate   bool
+	segments []string
+	err      error
+}
+
+func newIgnoreRule(raw string, origin Origin) IgnoreRule {
+	r := IgnoreRule{Raw: raw, Origin: origin}
+	pattern, negate := strings.CutPrefix(strings.TrimSpace(raw), "!")
+	r.negate = negate
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		r.err = errors.New("empty pattern")
+		return r
+	}
+	r.segments = strings.Split(strings.ToLower(pattern), "/")
+	for _, segment := range r.segments {
+		if _, err := path.Match(segment, ""); err != nil || segment == "" {
+			r.err = fmt.Errorf("invalid glob segment %q", segment)
+			return r
+		}
+	}
+	if !anchored {
+		r.segments = append([]string{"**"}, r.segments...)
+	}
+	return r
+}
+
+// Err returns the error of an invalid rule.
+func (r *IgnoreRule) Err() error {
+	return r.err
+}
+
+// Match reports whether the directory at rel, given as segments relative to
+// the repository root, matches the rule. Invalid rules never match.
+func (r *IgnoreRule) Match(rel []string) bool {
+	if r.err != nil {
+		return false
+	}
+	lower := make([]string, len(rel))
+	for i, segment := range rel {
+		lower[i] = strings.ToLower(segment)
+	}
+	return matchSegments(r.segments, lower)
+}
+
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			// A trailing "**" matches everything inside, not the directory
+			// itself.
+			return len(parts) > 0
+		}
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], parts[0])
+	return ok && matchSegments(pattern[1:], parts[1:])
+}
+
+// ignoreReason reports whether the directory dir, whose segments relative to
+// the repository root are rel, is excluded from environment detection, and
+// why. The last matching rule wins; a .cenvignore file in dir always excludes
+// it.
+func (c *Config) ignoreReason(rel []string, dir string) (string, bool) {
+	if _, err := os.Stat(filepath.Join(dir, ignoreMarkerName)); err == nil {
+		return fmt.Sprintf("ignored: contains %s", ignoreMarkerName), true
+	}
+	var matched *IgnoreRule
+	for i := range c.Ignore {
+		if c.Ignore[i].Match(rel) {
+			matched = &c.Ignore[i]
+		}
+	}
+	if matched == nil || matched.negate {
+		return "", false
+	}
+	return fmt.Sprintf("ignored by %q (%s)", matched.Raw, matched.Origin), true
+}
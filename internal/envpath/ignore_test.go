@@ -0,0 +1,135 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestResolveSkipsIgnoredSubtrees(t *testing.T) {
+	repo := setupRepo(t, `patterns = ["dev-*"]
+ignore = ["**/src/test", "dev-tools/"]
+`)
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{"anchored rule", filepath.Join(repo, "dev", "src", "test", "prod"), filepath.Join(repo, "prod", "src", "test", "prod")},
+		{"segment name at any depth", filepath.Join(repo, "dev", "charts", "dev-tools", "test"), filepath.Join(repo, "prod", "charts", "dev-tools", "test")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mustMkdirAll(t, tt.path)
+			result, err := envpath.Resolve(tt.path, "prod", envpath.Options{Occurrence: envpath.OccurrenceLast})
+			if err != nil {
+				t.Fatalf("Resolve returned error: %v", err)
+			}
+			if result.Target != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, result.Target)
+			}
+			if len(result.Candidates) != 1 {
+				t.Fatalf("expected ignored segments to drop out of the candidates, got %+v", result.Candidates)
+			}
+		})
+	}
+}
+
+func TestResolveAnchoredIgnoreRuleOnlyMatchesBelowRoot(t *testing.T) {
+	repo := setupRepo(t, `ignore = ["/test"]`)
+	path := filepath.Join(repo, "dev", "test")
+	mustMkdirAll(t, path)
+
+	result, err := envpath.Resolve(path, "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if len(result.Candidates) != 2 {
+		t.Fatalf("expected dev/test to remain a candidate, got %+v", result.Candidates)
+	}
+}
+
+func TestResolveNegatedIgnoreRuleReincludes(t *testing.T) {
+	repo := setupRepo(t, `ignore = ["test", "!modules/test"]`)
+	path := filepath.Join(repo, "modules", "test", "app")
+	mustMkdirAll(t, path)
+
+	result, err := envpath.Resolve(path, "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "modules", "prod", "app"); result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+}
+
+func TestResolveSkipsDirectoriesWithIgnoreMarker(t *testing.T) {
+	repo := setupRepo(t, "")
+	fixtures := filepath.Join(repo, "dev", "fixtures")
+	path := filepath.Join(fixtures, "prod", "app")
+	mustMkdirAll(t, path)
+	envpath.WriteFile(t, filepath.Join(fixtures, ".cenvignore"), "")
+
+	trace, err := envpath.Explain(path, "test", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Explain returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "test", "fixtures", "prod", "app"); trace.Result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, trace.Result.Target)
+	}
+	last := trace.Segments[len(trace.Segments)-1]
+	if last.Matched || !strings.Contains(last.Reason, `below ignored directory "fixtures"`) {
+		t.Fatalf("expected the subtree to be reported as ignored, got %+v", last)
+	}
+}
+
+func TestIgnoreRuleMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"test", "src/test", true},
+		{"test", "test", true},
+		{"test", "src/test/unit", false},
+		{"src/test", "src/test", true},
+		{"src/test", "modules/src/test", false},
+		{"**/src/test", "modules/src/test", true},
+		{"charts/**", "charts/dev/values", true},
+		{"charts/**", "charts", false},
+		{"Dev-*", "charts/dev-tools", true},
+	}
+	for _, tt := range tests {
+		rule := loadIgnoreRule(t, tt.pattern)
+		if got := rule.Match(strings.Split(tt.rel, "/")); got != tt.want {
+			t.Fatalf("%q matching %q: expected %t, got %t", tt.pattern, tt.rel, tt.want, got)
+		}
+	}
+}
+
+func loadIgnoreRule(t *testing.T, pattern string) *envpath.IgnoreRule {
+	t.Helper()
+	repo := setupRepo(t, "ignore = ['"+pattern+"']\n")
+	cfg, err := envpath.LoadConfig(repo, "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	if len(cfg.Ignore) != 1 || cfg.Ignore[0].Err() != nil {
+		t.Fatalf("expected one valid ignore rule, got %+v", cfg.Ignore)
+	}
+	return &cfg.Ignore[0]
+}
+
+func TestCheckReportsInvalidIgnoreRules(t *testing.T) {
+	repo := setupRepo(t, "ignore = [\"src/[test\", \"!\"]\n")
+	cfg, err := envpath.LoadConfig(repo, "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	issues := cfg.Check()
+	if len(issues) != 2 || !strings.Contains(issues[0].Message, `invalid ignore rule "src/[test"`) {
+		t.Fatalf("expected two invalid ignore rules, got %v", issues)
+	}
+}
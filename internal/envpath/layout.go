@@ -0,0 +1,120 @@
+package envpath
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnvDimension is the layout dimension that holds the environment directory.
+const EnvDimension = "env"
+
+// Layout is a path template with named dimensions, such as
+// "infra/{env}/{region}/{account}/{service}". Templates are matched below the
+// repository root, or at any depth when no root was found.
+type Layout struct {
+	Template string
+	Origin   Origin
+
+	segments []layoutSegment
+}
+
+type layoutSegment struct {
+	literal   string
+	dimension string
+}
+
+// Dimension is the value of a named layout dimension in a path.
+type Dimension struct {
+	Name  string
+	Value string
+	// Index is the position of the segment within the split path.
+	Index int
+}
+
+func parseLayout(template string, origin Origin) (Layout, error) {
+	layout := Layout{Template: template, Origin: origin}
+	seen := make(map[string]bool)
+	for _, segment := range strings.Split(strings.Trim(template, "/"), "/") {
+		if segment == "" {
+			return Layout{}, fmt.Errorf("layout %q contains an empty segment", template)
+		}
+		name, isDimension := strings.CutPrefix(segment, "{")
+		if !isDimension {
+			if strings.ContainsAny(segment, "{}") {
+				return Layout{}, fmt.Errorf("layout %q: segment %q must be a literal or a single {dimension}", template, segment)
+			}
+			layout.segments = append(layout.segments, layoutSegment{literal: segment})
+			continue
+		}
+		name, closed := strings.CutSuffix(name, "}")
+		if !closed || name == "" || strings.ContainsAny(name, "{}") {
+			return Layout{}, fmt.Errorf("layout %q: segment %q must be a literal or a single {dimension}", template, segment)
+		}
+		if seen[name] {
+			return Layout{}, fmt.Errorf("layout %q repeats dimension %q", template, name)
+		}
+		seen[name] = true
+		layout.segments = append(layout.segments, layoutSegment{dimension: name})
+	}
+	return layout, nil
+}
+
+// DimensionNames returns the dimensions of the layout in template order.
+func (l *Layout) DimensionNames() []string {
+	var names []string
+	for _, segment := range l.segments {
+		if segment.dimension != "" {
+			names = append(names, segment.dimension)
+		}
+	}
+	return names
+}
+
+// match parses parts starting at offset. Paths shallower than the template
+// match as long as every present segment does; deeper paths leave the rest as
+// a subpath. The env dimension must hold a recognised single-segment
+// environment directory.
+func (l *Layout) match(parts []string, offset int, cfg *Config, targetDir string) ([]Dimension, bool) {
+	var dims []Dimension
+	for i, segment := range l.segments {
+		idx := offset + i
+		if idx >= len(parts) {
+			break
+		}
+		part := parts[idx]
+		if segment.dimension == "" {
+			if !cfg.sameSegment(segment.literal, part) {
+				return nil, false
+			}
+			continue
+		}
+		if segment.dimension == EnvDimension {
+			if match, ok := cfg.detectEnv(parts[idx:idx+1], targetDir); !ok || match.length != 1 {
+				return nil, false
+			}
+		}
+		dims = append(dims, Dimension{Name: segment.dimension, Value: part, Index: idx})
+	}
+	return dims, len(dims) > 0
+}
+
+// matchLayout returns the first layout matching parts. With a repository root
+// the template must start directly below it at start; otherwise every offset
+// is tried from the outermost segment inwards.
+func (c *Config) matchLayout(parts []string, start int, anchored bool, targetDir string) (*Layout, []Dimension) {
+	for i := range c.Layouts {
+		layout := &c.Layouts[i]
+		if anchored {
+			if dims, ok := layout.match(parts, start, c, targetDir); ok {
+				return layout, dims
+			}
+			continue
+		}
+		for offset := start; offset < len(parts); offset++ {
+			if dims, ok := layout.match(parts, offset, c, targetDir); ok {
+				return layout, dims
+			}
+		}
+	}
+	return nil, nil
+}
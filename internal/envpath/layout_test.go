@@ -0,0 +1,95 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+const layoutConfig = `[[layout]]
+template = "infra/{env}/{region}/{account}/{service}"
+`
+
+func TestLocateParsesLayoutDimensions(t *testing.T) {
+	repo := setupRepo(t, layoutConfig)
+	path := filepath.Join(repo, "infra", "dev", "eu-west-1", "123456", "api", "modules")
+	mustMkdirAll(t, path)
+
+	loc, err := envpath.Locate(path, envpath.Options{})
+	if err != nil {
+		t.Fatalf("Locate returned error: %v", err)
+	}
+	if loc.Root != repo || loc.Env != "dev" {
+		t.Fatalf("unexpected location %+v", loc)
+	}
+	if loc.Layout == nil || loc.Layout.Template != "infra/{env}/{region}/{account}/{service}" {
+		t.Fatalf("expected layout to match, got %+v", loc.Layout)
+	}
+	want := map[string]string{"env": "dev", "region": "eu-west-1", "account": "123456", "service": "api"}
+	if len(loc.Dimensions) != len(want) {
+		t.Fatalf("unexpected dimensions %+v", loc.Dimensions)
+	}
+	for _, dim := range loc.Dimensions {
+		if want[dim.Name] != dim.Value {
+			t.Fatalf("dimension %s: expected %q, got %q", dim.Name, want[dim.Name], dim.Value)
+		}
+	}
+}
+
+func TestResolveSetsLayoutDimensions(t *testing.T) {
+	repo := setupRepo(t, layoutConfig)
+	path := filepath.Join(repo, "infra", "dev", "eu-west-1", "123456", "api")
+	mustMkdirAll(t, path)
+
+	result, err := envpath.Resolve(path, "", envpath.Options{Set: map[string]string{"region": "us-east-1", "env": "prod"}})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	expected := filepath.Join(repo, "infra", "prod", "us-east-1", "123456", "api")
+	if result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+	if result.EnvFrom != "dev" || result.EnvTo != "prod" {
+		t.Fatalf("unexpected environments %q -> %q", result.EnvFrom, result.EnvTo)
+	}
+
+	result, err = envpath.Resolve(path, "", envpath.Options{Set: map[string]string{"account": "999"}})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	expected = filepath.Join(repo, "infra", "dev", "eu-west-1", "999", "api")
+	if result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+}
+
+func TestResolveLayoutUsesEnvDimensionPosition(t *testing.T) {
+	repo := setupRepo(t, `[[layout]]
+template = "apps/{service}/{env}"
+`)
+	// The service is called "test"; only the {env} position may be replaced.
+	path := filepath.Join(repo, "apps", "test", "dev", "config")
+	mustMkdirAll(t, path)
+
+	target, err := envpath.Switch(path, "prod")
+	if err != nil {
+		t.Fatalf("Switch returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "apps", "test", "prod", "config"); target != expected {
+		t.Fatalf("expected %q, got %q", expected, target)
+	}
+}
+
+func TestResolveRejectsUnknownDimension(t *testing.T) {
+	repo := setupRepo(t, layoutConfig)
+	path := filepath.Join(repo, "infra", "dev", "eu-west-1")
+	mustMkdirAll(t, path)
+
+	if _, err := envpath.Resolve(path, "", envpath.Options{Set: map[string]string{"service": "api"}}); err == nil {
+		t.Fatalf("expected error when the dimension is deeper than the current path")
+	}
+	if _, err := envpath.Resolve(path, "", envpath.Options{Set: map[string]string{"cluster": "a"}}); err == nil {
+		t.Fatalf("expected error for a dimension that is not in the layout")
+	}
+}
@@ -0,0 +1,144 @@
+package envpath
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Counterpart is an environment that can be switched to from a path.
+type Counterpart struct {
+	// Env is the environment name.
+	Env string
+	// Dir is the environment directory next to the current one: the
+	// directory found on disk, or the configured one when it is missing.
+	Dir string
+	// Path is the counterpart of the path in Env. It is empty when Err is set.
+	Path string
+	// Current is set for the environment the path is in.
+	Current bool
+	// Configured is set for environments from the configuration, as opposed
+	// to directories only found on disk.
+	Configured bool
+	// Known is set for environments that are configured, declared by a
+	// marker file or matched by a pattern, as opposed to directories that
+	// merely sit next to the environment root.
+	Known bool
+	// Exists reports whether Path exists as a directory.
+	Exists bool
+	// Err is set when the counterpart could not be resolved.
+	Err error
+}
+
+// Counterparts lists the environments that can be switched to from path: the
+// configured environments followed by every other directory next to the
+// detected environment root, each with the counterpart of path in it.
+func Counterparts(path string, opts Options) ([]Counterpart, error) {
+	if path == "" {
+		return nil, errors.New("path must not be empty")
+	}
+	cleanPath := filepath.Clean(path)
+	cfg, err := opts.config(cleanPath)
+	if err != nil {
+		return nil, err
+	}
+	return counterparts(cleanPath, cfg, opts)
+}
+
+// counterparts implements Counterparts for a cleaned path and its loaded
+// configuration.
+func counterparts(cleanPath string, cfg *Config, opts Options) ([]Counterpart, error) {
+	policy, _, err := occurrencePolicy(opts.Occurrence, cfg)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := locate(cleanPath, cfg, opts, policy, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if loc.envIndex == -1 {
+		return nil, loc.notInEnvError(cleanPath)
+	}
+
+	var list []Counterpart
+	add := func(counterpart Counterpart) *Counterpart {
+		for i := range list {
+			if strings.EqualFold(list[i].Env, counterpart.Env) {
+				return &list[i]
+			}
+		}
+		list = append(list, counterpart)
+		return &list[len(list)-1]
+	}
+	for _, env := range cfg.Envs {
+		add(Counterpart{Env: env.Name, Dir: env.Dir(), Configured: true, Known: true})
+	}
+
+	siblings, err := cfg.siblingEnvs(loc)
+	if err != nil {
+		return nil, err
+	}
+	for _, sibling := range siblings {
+		counterpart := add(sibling)
+		counterpart.Dir = sibling.Dir
+		counterpart.Known = counterpart.Known || sibling.Known
+	}
+
+	for i := range list {
+		counterpart := &list[i]
+		counterpart.Current = strings.EqualFold(counterpart.Env, loc.envFrom)
+		counterpart.Known = counterpart.Known || counterpart.Current
+		result, err := resolve(cleanPath, counterpart.Env, opts, cfg, nil)
+		if err != nil {
+			counterpart.Err = err
+			continue
+		}
+		counterpart.Path, counterpart.Exists = result.Target, result.Exists
+	}
+	return list, nil
+}
+
+// siblingEnvs returns the directories next to the chosen environment root in
+// loc, sorted by name. Hidden and ignored directories are skipped.
+// Directories are named after the environment they hold, or the one declared
+// by their marker file, and are Known when either is configured or declared,
+// or a pattern matches them.
+func (c *Config) siblingEnvs(loc *location) ([]Counterpart, error) {
+	parentParts := loc.parts[:loc.envIndex]
+	entries, err := os.ReadDir(loc.path(parentParts))
+	if err != nil {
+		return nil, nil
+	}
+	base := 0
+	if loc.envIndex >= loc.start {
+		base = loc.start
+	}
+
+	var siblings []Counterpart
+	for _, entry := range entries {
+		name := entry.Name()
+		dir := loc.path(slices.Concat(parentParts, []string{name}))
+		if strings.HasPrefix(name, ".") || !isDir(dir) {
+			continue
+		}
+		if _, ignored := c.ignoreReason(slices.Concat(parentParts[base:], []string{name}), dir); ignored {
+			continue
+		}
+		sibling := Counterpart{Env: name, Dir: name}
+		marker, err := readEnvMarker(dir, c.envMarkerName())
+		if err != nil {
+			return nil, err
+		}
+		if marker != nil {
+			sibling.Env, sibling.Known = marker.Env, true
+		} else if env, ok := c.EnvForDir(name); ok {
+			sibling.Env, sibling.Known = env.Name, true
+		} else if _, ok := c.matchPattern(name); ok {
+			sibling.Known = true
+		}
+		siblings = append(siblings, sibling)
+	}
+	return siblings, nil
+}
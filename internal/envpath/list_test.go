@@ -0,0 +1,48 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestCounterpartsCombinesConfigAndSiblings(t *testing.T) {
+	repo := setupRepo(t, "ignore = [\"shared\"]\n")
+	devPath := filepath.Join(repo, "dev", "app")
+	mustMkdirAll(t, devPath)
+	mustMkdirAll(t, filepath.Join(repo, "Prod"))
+	mustMkdirAll(t, filepath.Join(repo, "staging", "app"))
+	mustMkdirAll(t, filepath.Join(repo, "shared"))
+	mustMkdirAll(t, filepath.Join(repo, ".cache"))
+
+	list, err := envpath.Counterparts(devPath, envpath.Options{})
+	if err != nil {
+		t.Fatalf("Counterparts returned error: %v", err)
+	}
+
+	expected := []envpath.Counterpart{
+		{Env: "dev", Dir: "dev", Path: devPath, Current: true, Configured: true, Known: true, Exists: true},
+		{Env: "test", Dir: "test", Path: filepath.Join(repo, "test", "app"), Configured: true, Known: true},
+		{Env: "prod", Dir: "Prod", Path: filepath.Join(repo, "Prod", "app"), Configured: true, Known: true},
+		{Env: "staging", Dir: "staging", Path: filepath.Join(repo, "staging", "app"), Exists: true},
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), list)
+	}
+	for i, want := range expected {
+		if list[i] != want {
+			t.Fatalf("entry %d: expected %+v, got %+v", i, want, list[i])
+		}
+	}
+}
+
+func TestCounterpartsRequiresEnvironment(t *testing.T) {
+	envpath.IsolateConfig(t)
+	dir := filepath.Join(t.TempDir(), "services")
+	mustMkdirAll(t, dir)
+
+	if _, err := envpath.Counterparts(dir, envpath.Options{}); err == nil {
+		t.Fatalf("expected error for a path outside any environment")
+	}
+}
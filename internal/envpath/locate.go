@@ -0,0 +1,300 @@
+package envpath
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Location describes how a path is interpreted: its repository root, the
+// environment directory it sits in, and the layout dimensions it encodes.
+type Location struct {
+	// Path is the cleaned input path.
+	Path string
+	// Root is the repository root, or empty when no root marker was found.
+	Root string
+	// Env is the name of the detected environment, or empty if none.
+	Env string
+	// EnvDir is the environment directory as it appears in Path. It spans
+	// several segments for environments such as "prod/eu".
+	EnvDir string
+	// Subpath is the part of Path below the environment directory.
+	Subpath string
+	// Layout is the matching layout template, or nil.
+	Layout *Layout
+	// Dimensions holds the values of the layout dimensions in Path.
+	Dimensions []Dimension
+	// Candidates lists every environment directory found in Path. Env is
+	// taken from the one selected by the occurrence policy.
+	Candidates []Candidate
+	// Marker is the marker file declaring the environment root, or nil when
+	// the environment was recognised by name.
+	Marker *EnvMarker
+}
+
+// location is the parsed form of a path shared by Locate and Resolve.
+type location struct {
+	volume     string
+	hasLeading bool
+	parts      []string
+	root       string
+	hasRoot    bool
+	start      int
+	envIndex   int
+	envLen     int
+	envFrom    string
+	layout     *Layout
+	dims       []Dimension
+	policy     Occurrence
+	candidates []Candidate
+}
+
+// Locate parses path into its repository root, environment and layout
+// dimensions without switching anything.
+func Locate(path string, opts Options) (*Location, error) {
+	if path == "" {
+		return nil, errors.New("path must not be empty")
+	}
+	cleanPath := filepath.Clean(path)
+	cfg, err := opts.config(cleanPath)
+	if err != nil {
+		return nil, err
+	}
+	policy, _, err := occurrencePolicy(opts.Occurrence, cfg)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := locate(cleanPath, cfg, opts, policy, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Location{
+		Path:       cleanPath,
+		Root:       loc.root,
+		Env:        loc.envFrom,
+		Layout:     loc.layout,
+		Dimensions: loc.dims,
+		Candidates: loc.candidates,
+	}
+	if chosen, ok := loc.chosen(); ok {
+		result.Marker = chosen.Marker
+	}
+	if loc.envIndex >= 0 {
+		result.EnvDir = filepath.Join(loc.parts[loc.envIndex : loc.envIndex+loc.envLen]...)
+		result.Subpath = filepath.Join(loc.parts[loc.envIndex+loc.envLen:]...)
+	}
+	return result, nil
+}
+
+// locate splits path, finds the repository root and detects the runs of
+// segments forming environment directories, choosing one according to policy.
+// targetDir additionally counts as an environment directory so that
+// unconfigured targets can be switched back. Each step is recorded in tr, which
+// may be nil. Directories containing an environment marker file are
+// recognised regardless of their name.
+func locate(path string, cfg *Config, opts Options, policy Occurrence, targetDir string, tr *Trace) (*location, error) {
+	loc := &location{envIndex: -1, policy: policy}
+	loc.volume, loc.hasLeading, loc.parts = splitPath(path)
+
+	markers := rootMarkers(opts.RootMarkers, cfg)
+	loc.root, loc.hasRoot = FindRoot(path, markers)
+	if loc.hasRoot {
+		_, _, rootParts := splitPath(loc.root)
+		loc.start = len(rootParts)
+	}
+	if tr != nil {
+		tr.Volume, tr.Absolute, tr.Parts = loc.volume, loc.hasLeading, slices.Clone(loc.parts)
+		tr.Root, tr.RootMarkers, tr.Occurrence = loc.root, markers, policy
+	}
+
+	loc.layout, loc.dims = cfg.matchLayout(loc.parts, loc.start, loc.hasRoot, targetDir)
+	if tr != nil {
+		tr.Layout = loc.layout
+	}
+	for _, dim := range loc.dims {
+		if dim.Name == EnvDimension {
+			for idx := range min(dim.Index, len(loc.parts)) {
+				tr.segment(SegmentCheck{Index: idx, Segment: loc.parts[idx], Reason: "before the layout"})
+			}
+			match, _ := cfg.detectEnv(loc.parts[dim.Index:dim.Index+1], targetDir)
+			loc.choose([]Candidate{{Index: dim.Index, Len: 1, Env: match.name, Dir: loc.parts[dim.Index]}}, tr)
+			tr.segment(SegmentCheck{
+				Index:   dim.Index,
+				Segment: loc.parts[dim.Index],
+				Matched: true,
+				Env:     match.name,
+				Reason:  fmt.Sprintf("{%s} dimension of layout %q; %s", EnvDimension, loc.layout.Template, match.reason),
+			})
+			return loc, nil
+		}
+	}
+
+	searchStart := loc.start
+	if policy.ignoresRoot() {
+		searchStart = 0
+	}
+	for idx := range min(searchStart, len(loc.parts)) {
+		tr.segment(SegmentCheck{Index: idx, Segment: loc.parts[idx], Reason: "above the repository root"})
+	}
+
+	var candidates []Candidate
+	covered := searchStart
+	for idx := searchStart; idx < len(loc.parts); idx++ {
+		base := 0
+		if idx >= loc.start {
+			base = loc.start
+		}
+		if reason, ok := cfg.ignoreReason(loc.parts[base:idx+1], loc.path(loc.parts[:idx+1])); ok {
+			tr.segment(SegmentCheck{Index: idx, Segment: loc.parts[idx], Reason: reason})
+			for below := idx + 1; below < len(loc.parts); below++ {
+				tr.segment(SegmentCheck{Index: below, Segment: loc.parts[below], Reason: fmt.Sprintf("below ignored directory %q", loc.parts[idx])})
+			}
+			break
+		}
+		if idx < covered {
+			last := candidates[len(candidates)-1]
+			tr.segment(SegmentCheck{Index: idx, Segment: loc.parts[idx], Matched: true, Env: last.Env, Reason: fmt.Sprintf("part of environment directory %q", last.Dir)})
+			continue
+		}
+		marker, err := readEnvMarker(loc.path(loc.parts[:idx+1]), cfg.envMarkerName())
+		if err != nil {
+			return nil, err
+		}
+		if marker != nil {
+			name := marker.Env
+			if env, ok := cfg.Lookup(name); ok {
+				name = env.Name
+			}
+			tr.segment(SegmentCheck{Index: idx, Segment: loc.parts[idx], Matched: true, Env: name, Reason: fmt.Sprintf("%s marker declares %q", cfg.envMarkerName(), marker.Env)})
+			candidates = append(candidates, Candidate{Index: idx, Len: 1, Env: name, Dir: loc.parts[idx], Marker: marker})
+			covered = idx + 1
+			continue
+		}
+		match, ok := cfg.detectEnv(loc.parts[idx:], targetDir)
+		tr.segment(SegmentCheck{Index: idx, Segment: loc.parts[idx], Matched: ok, Env: match.name, Reason: match.reason})
+		if ok {
+			dir := strings.Join(loc.parts[idx:idx+match.length], "/")
+			candidates = append(candidates, Candidate{Index: idx, Len: match.length, Env: match.name, Dir: dir})
+			covered = idx + match.length
+		}
+	}
+	loc.choose(candidates, tr)
+	return loc, nil
+}
+
+// choose records the candidates and selects the environment directory
+// according to the occurrence policy.
+func (l *location) choose(candidates []Candidate, tr *Trace) {
+	l.candidates = candidates
+	if len(candidates) > 0 {
+		chosen := candidates[l.policy.choose(candidates)]
+		l.envIndex, l.envLen, l.envFrom = chosen.Index, chosen.Len, chosen.Env
+	}
+	if tr != nil {
+		tr.Candidates = candidates
+		tr.EnvIndex, tr.EnvLen = l.envIndex, l.envLen
+	}
+}
+
+// chosen returns the candidate selected by the occurrence policy.
+func (l *location) chosen() (Candidate, bool) {
+	for _, candidate := range l.candidates {
+		if candidate.Index == l.envIndex {
+			return candidate, true
+		}
+	}
+	return Candidate{}, false
+}
+
+// replaced returns the candidates that are switched: every one under
+// OccurrenceReplaceAll, otherwise only the chosen one.
+func (l *location) replaced() []Candidate {
+	if l.policy == OccurrenceReplaceAll {
+		return l.candidates
+	}
+	if chosen, ok := l.chosen(); ok {
+		return []Candidate{chosen}
+	}
+	return nil
+}
+
+// envMatch describes an environment directory found at the start of a run of
+// path segments.
+type envMatch struct {
+	name   string
+	length int
+	reason string
+}
+
+// detectEnv reports whether parts start with an environment directory and
+// returns the environment name and the number of segments it spans. The
+// longest configured directory wins, so "prod/eu" takes precedence over
+// "prod". targetDir and pattern matches span one segment and are named after
+// the segment itself. The reason explains the outcome either way.
+func (c *Config) detectEnv(parts []string, targetDir string) (envMatch, bool) {
+	if len(parts) == 0 {
+		return envMatch{}, false
+	}
+	if env, length := c.envForSegments(parts); env != nil {
+		dir := strings.Join(parts[:length], "/")
+		return envMatch{env.Name, length, fmt.Sprintf("directory %q of environment %q", dir, env.Name)}, true
+	}
+	if targetDir != "" && c.sameSegment(parts[0], targetDir) {
+		return envMatch{parts[0], 1, "matches the target directory"}, true
+	}
+	if pattern, ok := c.matchPattern(parts[0]); ok {
+		return envMatch{parts[0], 1, fmt.Sprintf("matches pattern %q (%s)", pattern.Raw, pattern.Origin)}, true
+	}
+	return envMatch{reason: "not a known environment directory or pattern"}, false
+}
+
+// envForSegments returns the environment whose directory matches the longest
+// prefix of parts, and the length of that prefix.
+func (c *Config) envForSegments(parts []string) (*Env, int) {
+	var (
+		best    *Env
+		bestLen int
+	)
+	for i := range c.Envs {
+		for _, dir := range c.Envs[i].DirNames() {
+			segments := dirSegments(dir)
+			if len(segments) <= bestLen || len(segments) > len(parts) {
+				continue
+			}
+			if slices.EqualFunc(segments, parts[:len(segments)], c.sameSegment) {
+				best, bestLen = &c.Envs[i], len(segments)
+			}
+		}
+	}
+	return best, bestLen
+}
+
+// dirSegments splits a configured environment directory such as "prod/eu"
+// into path segments.
+func dirSegments(dir string) []string {
+	return strings.Split(strings.Trim(dir, "/"), "/")
+}
+
+// notInEnvError reports that path, parsed as l, has no environment segment.
+func (l *location) notInEnvError(path string) error {
+	if l.hasRoot {
+		return &NotInEnvError{Path: path, Root: l.root}
+	}
+	return &NotInEnvError{Path: path}
+}
+
+func (l *location) path(parts []string) string {
+	return assemblePath(l.volume, l.hasLeading, parts)
+}
+
+func (l *location) dimension(name string) (Dimension, bool) {
+	for _, dim := range l.dims {
+		if dim.Name == name {
+			return dim, true
+		}
+	}
+	return Dimension{}, false
+}
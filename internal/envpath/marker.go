@@ -0,0 +1,128 @@
+package envpath
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultEnvMarker is the file that declares its directory an environment
+// root when env_marker is not configured.
+const defaultEnvMarker = ".cenv-env"
+
+// EnvMarker is an environment root declared by a marker file rather than
+// recognised by its directory name, for example a directory named after an
+// account ID. The marker is a TOML file:
+//
+//	env = "prod"
+//	description = "Production account"
+//
+//	[meta]
+//	account = "123456789012"
+type EnvMarker struct {
+	// Path is the marker file.
+	Path        string
+	Env         string
+	Description string
+	Meta        map[string]string
+}
+
+// envMarkerName returns the configured marker file name.
+func (c *Config) envMarkerName() string {
+	if c.EnvMarker.Value != "" {
+		return c.EnvMarker.Value
+	}
+	return defaultEnvMarker
+}
+
+// readEnvMarker reads the marker file called name in dir. It returns nil
+// without an error when dir has no marker.
+func readEnvMarker(dir, name string) (*EnvMarker, error) {
+	path := filepath.Join(dir, name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || !isDir(dir) || isDir(path) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read environment marker: %w", err)
+	}
+	root, err := parseTOML(string(data))
+	if err != nil {
+		var tErr *tomlError
+		if errors.As(err, &tErr) {
+			return nil, fmt.Errorf("%s:%d: %s", path, tErr.line, tErr.msg)
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	d := &configDecoder{path: path}
+	marker := &EnvMarker{Path: path}
+	for _, key := range root.keys {
+		value := root.values[key]
+		switch key {
+		case "env":
+			if marker.Env, err = d.string(key, value); err != nil {
+				return nil, err
+			}
+			marker.Env = strings.TrimSpace(marker.Env)
+			if marker.Env == "" || hasPathSeparator(marker.Env) {
+				return nil, d.errorf(value.line, "env must be a non-empty name without path separators")
+			}
+		case "description":
+			if marker.Description, err = d.string(key, value); err != nil {
+				return nil, err
+			}
+		case "meta":
+			meta, err := d.table(key, value)
+			if err != nil {
+				return nil, err
+			}
+			marker.Meta = make(map[string]string, len(meta.keys))
+			for _, metaKey := range meta.keys {
+				if marker.Meta[metaKey], err = d.string(key+"."+metaKey, meta.values[metaKey]); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			return nil, d.errorf(value.line, "unknown key %q", key)
+		}
+	}
+	if marker.Env == "" {
+		return nil, fmt.Errorf("%s: missing env", path)
+	}
+	return marker, nil
+}
+
+// markerSibling returns the directory next to a marker-declared environment
+// root, inside parent, whose marker declares env.
+func (c *Config) markerSibling(parent, env string, tr *Trace) (string, error) {
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return "", fmt.Errorf("find environment %q: %w", env, err)
+	}
+	var matches []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		marker, err := readEnvMarker(filepath.Join(parent, entry.Name()), c.envMarkerName())
+		if err != nil {
+			return "", err
+		}
+		if marker != nil && strings.EqualFold(marker.Env, env) {
+			matches = append(matches, entry.Name())
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", &UnknownTargetError{Target: env, Dir: parent, Marker: c.envMarkerName()}
+	case 1:
+		tr.step("%s marker in %q declares %q", c.envMarkerName(), matches[0], env)
+		return matches[0], nil
+	default:
+		return "", &AmbiguousSegmentError{Path: parent, Segment: env, Candidates: matches, Marker: c.envMarkerName()}
+	}
+}
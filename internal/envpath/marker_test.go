@@ -0,0 +1,89 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+// writeAccountMarkers declares the staging and prod environments in marker
+// files of two account directories below repo.
+func writeAccountMarkers(t *testing.T, repo string) {
+	t.Helper()
+	for account, env := range map[string]string{"111111111111": "staging", "222222222222": "prod"} {
+		mustMkdirAll(t, filepath.Join(repo, "accounts", account, "network"))
+		envpath.WriteFile(t, filepath.Join(repo, "accounts", account, ".cenv-env"), "env = \""+env+"\"\n\n[meta]\naccount = \""+account+"\"\n")
+	}
+}
+
+func TestResolveSwitchesBetweenMarkerRoots(t *testing.T) {
+	repo := setupRepo(t, "")
+	writeAccountMarkers(t, repo)
+	from := filepath.Join(repo, "accounts", "111111111111", "network")
+
+	result, err := envpath.Resolve(from, "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	expected := filepath.Join(repo, "accounts", "222222222222", "network")
+	if result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+	if result.EnvFrom != "staging" || result.EnvTo != "prod" {
+		t.Fatalf("expected staging -> prod, got %q -> %q", result.EnvFrom, result.EnvTo)
+	}
+}
+
+func TestLocateReportsMarkerMetadata(t *testing.T) {
+	repo := setupRepo(t, "")
+	writeAccountMarkers(t, repo)
+
+	loc, err := envpath.Locate(filepath.Join(repo, "accounts", "222222222222", "network"), envpath.Options{})
+	if err != nil {
+		t.Fatalf("Locate returned error: %v", err)
+	}
+	if loc.Env != "prod" || loc.Marker == nil || loc.Marker.Meta["account"] != "222222222222" {
+		t.Fatalf("unexpected location %+v", loc)
+	}
+}
+
+func TestResolveFailsWithoutMatchingMarkerSibling(t *testing.T) {
+	repo := setupRepo(t, "")
+	writeAccountMarkers(t, repo)
+	from := filepath.Join(repo, "accounts", "111111111111", "network")
+
+	_, err := envpath.Resolve(from, "dev", envpath.Options{})
+	if err == nil || !strings.Contains(err.Error(), `.cenv-env marker declaring "dev"`) {
+		t.Fatalf("expected missing marker error, got %v", err)
+	}
+}
+
+func TestResolveRejectsMalformedMarker(t *testing.T) {
+	repo := setupRepo(t, "")
+	writeAccountMarkers(t, repo)
+	marker := filepath.Join(repo, "accounts", "111111111111", ".cenv-env")
+	envpath.WriteFile(t, marker, "env = \"staging\"\nregion = \"eu\"\n")
+
+	_, err := envpath.Resolve(filepath.Join(repo, "accounts", "111111111111", "network"), "prod", envpath.Options{})
+	if err == nil || !strings.Contains(err.Error(), marker+`:2: unknown key "region"`) {
+		t.Fatalf("expected error naming the marker line, got %v", err)
+	}
+}
+
+func TestResolveUsesConfiguredMarkerName(t *testing.T) {
+	repo := setupRepo(t, "env_marker = \"ENVIRONMENT\"\n")
+	mustMkdirAll(t, filepath.Join(repo, "a", "app"))
+	mustMkdirAll(t, filepath.Join(repo, "b"))
+	envpath.WriteFile(t, filepath.Join(repo, "a", "ENVIRONMENT"), "env = \"qa\"\n")
+	envpath.WriteFile(t, filepath.Join(repo, "b", "ENVIRONMENT"), "env = \"uat\"\n")
+
+	result, err := envpath.Resolve(filepath.Join(repo, "a", "app"), "uat", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "b", "app"); result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+}
@@ -0,0 +1,99 @@
+package envpath
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Matrix records which directories of an environment subtree exist in every
+// other environment.
+type Matrix struct {
+	// Path is the directory whose subtree was walked.
+	Path string
+	// Envs lists the environments compared, as returned by Counterparts.
+	Envs []string
+	// Rows holds one entry per directory, in walk order.
+	Rows []MatrixRow
+}
+
+// MatrixRow is one directory of a Matrix.
+type MatrixRow struct {
+	// Subpath is the directory relative to Matrix.Path; "." is Path itself.
+	Subpath string
+	// Present is indexed like Matrix.Envs and reports whether the
+	// counterpart of the directory exists in each environment.
+	Present []bool
+}
+
+// BuildMatrix walks the directories below path, which must lie inside an
+// environment, and resolves each one in every known environment that
+// Counterparts lists, see Counterpart.Known. depth limits how many levels
+// below path are walked; zero or less means no limit. Hidden directories are
+// skipped.
+func BuildMatrix(path string, depth int, opts Options) (*Matrix, error) {
+	if path == "" {
+		return nil, errors.New("path must not be empty")
+	}
+	cleanPath := filepath.Clean(path)
+	cfg, err := opts.config(cleanPath)
+	if err != nil {
+		return nil, err
+	}
+	all, err := counterparts(cleanPath, cfg, opts)
+	if err != nil {
+		return nil, err
+	}
+	var list []Counterpart
+	for _, counterpart := range all {
+		if counterpart.Known {
+			list = append(list, counterpart)
+		}
+	}
+
+	matrix := &Matrix{Path: cleanPath}
+	for _, counterpart := range list {
+		matrix.Envs = append(matrix.Envs, counterpart.Env)
+	}
+
+	err = filepath.WalkDir(cleanPath, func(dir string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(cleanPath, dir)
+		if err != nil {
+			return err
+		}
+		if rel != "." {
+			if strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if depth > 0 && len(strings.Split(rel, string(filepath.Separator))) > depth {
+				return filepath.SkipDir
+			}
+		}
+
+		row := MatrixRow{Subpath: filepath.ToSlash(rel), Present: make([]bool, len(list))}
+		for i, counterpart := range list {
+			if counterpart.Current {
+				row.Present[i] = true
+				continue
+			}
+			if counterpart.Err != nil {
+				continue
+			}
+			result, err := resolve(dir, counterpart.Env, opts, cfg, nil)
+			row.Present[i] = err == nil && result.Exists
+		}
+		matrix.Rows = append(matrix.Rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return matrix, nil
+}
@@ -0,0 +1,43 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestBuildMatrixMarksMissingCounterparts(t *testing.T) {
+	repo := setupRepo(t, "", "dev/app/config/overlays/", "dev/app/.cache/", "prod/app/config/", "docs/app/")
+
+	matrix, err := envpath.BuildMatrix(filepath.Join(repo, "dev", "app"), 0, envpath.Options{})
+	if err != nil {
+		t.Fatalf("BuildMatrix returned error: %v", err)
+	}
+	if !slices.Equal(matrix.Envs, []string{"dev", "test", "prod"}) {
+		t.Fatalf("unexpected envs %q", matrix.Envs)
+	}
+	expected := []envpath.MatrixRow{
+		{Subpath: ".", Present: []bool{true, false, true}},
+		{Subpath: "config", Present: []bool{true, false, true}},
+		{Subpath: "config/overlays", Present: []bool{true, false, false}},
+	}
+	if len(matrix.Rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %+v", len(expected), matrix.Rows)
+	}
+	for i, want := range expected {
+		got := matrix.Rows[i]
+		if got.Subpath != want.Subpath || !slices.Equal(got.Present, want.Present) {
+			t.Fatalf("row %d: expected %+v, got %+v", i, want, got)
+		}
+	}
+
+	matrix, err = envpath.BuildMatrix(filepath.Join(repo, "dev", "app"), 1, envpath.Options{})
+	if err != nil {
+		t.Fatalf("BuildMatrix returned error: %v", err)
+	}
+	if len(matrix.Rows) != 2 {
+		t.Fatalf("expected --depth 1 to stop below config, got %+v", matrix.Rows)
+	}
+}
@@ -0,0 +1,90 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func setupMultiSegmentConfig(t *testing.T) {
+	t.Helper()
+	home := envpath.IsolateConfig(t)
+	envpath.WriteFile(t, filepath.Join(home, ".config", "changeenv", "config.toml"), `[env.prod-eu]
+dirs = ["prod/eu"]
+[env.prod-us]
+dirs = ["prod/us"]
+`)
+}
+
+func TestSwitchRoundTripsMultiSegmentEnvironments(t *testing.T) {
+	setupMultiSegmentConfig(t)
+
+	root := t.TempDir()
+	devPath := filepath.Join(root, "live", "dev", "app")
+	prodPath := filepath.Join(root, "live", "prod", "eu", "app")
+	mustMkdirAll(t, devPath)
+	mustMkdirAll(t, prodPath)
+
+	target, err := envpath.Switch(devPath, "prod-eu")
+	if err != nil {
+		t.Fatalf("Switch returned error: %v", err)
+	}
+	if target != prodPath {
+		t.Fatalf("expected %q, got %q", prodPath, target)
+	}
+
+	result, err := envpath.Resolve(prodPath, "dev", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if result.Target != devPath || result.EnvFrom != "prod-eu" {
+		t.Fatalf("expected %q from prod-eu, got %q from %q", devPath, result.Target, result.EnvFrom)
+	}
+
+	target, err = envpath.Switch(prodPath, "prod-us")
+	if err != nil {
+		t.Fatalf("Switch returned error: %v", err)
+	}
+	if expected := filepath.Join(root, "live", "prod", "us", "app"); target != expected {
+		t.Fatalf("expected %q, got %q", expected, target)
+	}
+}
+
+func TestResolveNearestKeepsWholeMultiSegmentEnvironment(t *testing.T) {
+	setupMultiSegmentConfig(t)
+
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "app", "config")
+	mustMkdirAll(t, devPath)
+	mustMkdirAll(t, filepath.Join(root, "prod", "eu"))
+
+	result, err := envpath.Resolve(devPath, "prod-eu", envpath.Options{Nearest: true})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(root, "prod", "eu"); result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+
+	mustMkdirAll(t, filepath.Join(root, "prod", "us-only"))
+	if _, err := envpath.Resolve(devPath, "prod-us", envpath.Options{Nearest: true}); err == nil {
+		t.Fatalf("expected error when only part of the environment directory exists")
+	}
+}
+
+func TestLocateReportsMultiSegmentEnvDir(t *testing.T) {
+	setupMultiSegmentConfig(t)
+
+	root := t.TempDir()
+	path := filepath.Join(root, "prod", "eu", "app")
+	mustMkdirAll(t, path)
+
+	loc, err := envpath.Locate(path, envpath.Options{})
+	if err != nil {
+		t.Fatalf("Locate returned error: %v", err)
+	}
+	if loc.Env != "prod-eu" || loc.EnvDir != filepath.Join("prod", "eu") || loc.Subpath != "app" {
+		t.Fatalf("unexpected location %+v", loc)
+	}
+}
@@ -0,0 +1,94 @@
+package envpath
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Occurrence selects which environment segment is switched when a path
+// contains several of them, such as "infra/prod/tools/dev-fixtures/test".
+type Occurrence string
+
+const (
+	// OccurrenceInnermostUnderRoot switches the outermost environment segment
+	// below the innermost repository root. It is the default.
+	OccurrenceInnermostUnderRoot Occurrence = "innermost-under-root"
+	// OccurrenceFirst switches the outermost environment segment anywhere in
+	// the path, ignoring the repository root.
+	OccurrenceFirst Occurrence = "first"
+	// OccurrenceLast switches the innermost environment segment anywhere in
+	// the path, ignoring the repository root.
+	OccurrenceLast Occurrence = "last"
+	// OccurrenceReplaceAll switches every environment segment below the
+	// repository root.
+	OccurrenceReplaceAll Occurrence = "replace-all"
+)
+
+var occurrences = []Occurrence{OccurrenceFirst, OccurrenceLast, OccurrenceInnermostUnderRoot, OccurrenceReplaceAll}
+
+// ParseOccurrence validates an occurrence policy name.
+func ParseOccurrence(name string) (Occurrence, error) {
+	for _, occurrence := range occurrences {
+		if strings.EqualFold(name, string(occurrence)) {
+			return occurrence, nil
+		}
+	}
+	names := make([]string, len(occurrences))
+	for i, occurrence := range occurrences {
+		names[i] = string(occurrence)
+	}
+	return "", fmt.Errorf("unknown occurrence policy %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// ignoresRoot reports whether the policy searches the whole path.
+func (o Occurrence) ignoresRoot() bool {
+	return o == OccurrenceFirst || o == OccurrenceLast
+}
+
+// Candidate is a run of path segments recognised as an environment directory.
+type Candidate struct {
+	// Index is the position of the first segment within the split path.
+	Index int
+	// Len is the number of segments the directory spans.
+	Len int
+	// Env is the environment the directory belongs to.
+	Env string
+	// Dir is the directory as it appears in the path.
+	Dir string
+	// Marker is the marker file that declared the directory an environment
+	// root, or nil when it was recognised by name.
+	Marker *EnvMarker
+}
+
+// occurrencePolicy returns the policy in effect: the explicit one when given,
+// otherwise the configured one, otherwise the default. The second return value
+// reports whether the policy was chosen rather than defaulted.
+func occurrencePolicy(explicit Occurrence, cfg *Config) (Occurrence, bool, error) {
+	if explicit != "" {
+		occurrence, err := ParseOccurrence(string(explicit))
+		return occurrence, true, err
+	}
+	if cfg.Occurrence.Value != "" {
+		return cfg.Occurrence.Value, true, nil
+	}
+	return OccurrenceInnermostUnderRoot, false, nil
+}
+
+// choose returns the index in candidates of the segment that policy switches.
+func (o Occurrence) choose(candidates []Candidate) int {
+	if o == OccurrenceLast {
+		return len(candidates) - 1
+	}
+	return 0
+}
+
+// occurrenceWarning describes several candidates found under the default
+// policy.
+func occurrenceWarning(candidates []Candidate, chosen Candidate) string {
+	described := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		described[i] = fmt.Sprintf("%q at segment %d", candidate.Dir, candidate.Index)
+	}
+	return fmt.Sprintf("found %d environment segments (%s); switched %q at segment %d (set occurrence or pass --occurrence to choose)",
+		len(candidates), strings.Join(described, ", "), chosen.Dir, chosen.Index)
+}
@@ -0,0 +1,116 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+// nestedEnvPath lies in a repository nested at dev/repo, whose root sits
+// between the dev and prod segments.
+const nestedEnvPath = "dev/repo/prod/tools/test/app/"
+
+func TestResolveOccurrencePolicies(t *testing.T) {
+	base := setupRepo(t, "", "dev/repo/.git/", nestedEnvPath)
+	repo, path := filepath.Join(base, "dev", "repo"), filepath.Join(base, nestedEnvPath)
+
+	tests := []struct {
+		occurrence envpath.Occurrence
+		expected   string
+	}{
+		{envpath.OccurrenceInnermostUnderRoot, filepath.Join(repo, "dev", "tools", "test", "app")},
+		{envpath.OccurrenceFirst, filepath.Join(base, "dev", "repo", "prod", "tools", "test", "app")},
+		{envpath.OccurrenceLast, filepath.Join(repo, "prod", "tools", "dev", "app")},
+		{envpath.OccurrenceReplaceAll, filepath.Join(repo, "dev", "tools", "dev", "app")},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.occurrence), func(t *testing.T) {
+			result, err := envpath.Resolve(path, "dev", envpath.Options{Occurrence: tt.occurrence})
+			if err != nil {
+				t.Fatalf("Resolve returned error: %v", err)
+			}
+			if result.Target != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, result.Target)
+			}
+			if len(result.Warnings) != 0 {
+				t.Fatalf("expected no warnings for an explicit policy, got %q", result.Warnings)
+			}
+		})
+	}
+}
+
+func TestResolveFirstPolicySwitchesAboveRoot(t *testing.T) {
+	base := setupRepo(t, "", "dev/repo/.git/", nestedEnvPath)
+	repo, path := filepath.Join(base, "dev", "repo"), filepath.Join(base, nestedEnvPath)
+
+	result, err := envpath.Resolve(path, "test", envpath.Options{Occurrence: envpath.OccurrenceFirst})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if result.EnvFrom != "dev" || len(result.Candidates) != 3 {
+		t.Fatalf("expected dev out of three candidates, got %q from %+v", result.EnvFrom, result.Candidates)
+	}
+	if strings.HasPrefix(result.Target, repo) {
+		t.Fatalf("expected the segment above the root to be switched, got %q", result.Target)
+	}
+}
+
+func TestResolveWarnsAboutSeveralCandidatesByDefault(t *testing.T) {
+	base := setupRepo(t, "", "dev/repo/.git/", nestedEnvPath)
+	repo, path := filepath.Join(base, "dev", "repo"), filepath.Join(base, nestedEnvPath)
+
+	result, err := envpath.Resolve(path, "dev", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if len(result.Candidates) != 2 {
+		t.Fatalf("expected the two candidates below the root, got %+v", result.Candidates)
+	}
+	if len(result.Warnings) != 1 || !strings.Contains(result.Warnings[0], `"prod" at segment`) || !strings.Contains(result.Warnings[0], `"test" at segment`) {
+		t.Fatalf("expected a warning naming both candidates, got %q", result.Warnings)
+	}
+	if expected := filepath.Join(repo, "dev", "tools", "test", "app"); result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+}
+
+func TestResolveUsesConfiguredOccurrence(t *testing.T) {
+	base := setupRepo(t, "occurrence = \"last\"\n", "dev/repo/.git/", nestedEnvPath)
+	repo, path := filepath.Join(base, "dev", "repo"), filepath.Join(base, nestedEnvPath)
+
+	result, err := envpath.Resolve(path, "dev", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "prod", "tools", "dev", "app"); result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+	if len(result.Warnings) != 0 {
+		t.Fatalf("expected no warnings for a configured policy, got %q", result.Warnings)
+	}
+
+	result, err = envpath.Resolve(path, "dev", envpath.Options{Occurrence: envpath.OccurrenceInnermostUnderRoot})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "dev", "tools", "test", "app"); result.Target != expected {
+		t.Fatalf("expected the option to override the config, got %q", result.Target)
+	}
+}
+
+func TestOccurrenceRejectsUnknownPolicy(t *testing.T) {
+	if _, err := envpath.ParseOccurrence("middle"); err == nil {
+		t.Fatalf("expected error for an unknown policy")
+	}
+	if occurrence, err := envpath.ParseOccurrence("Replace-All"); err != nil || occurrence != envpath.OccurrenceReplaceAll {
+		t.Fatalf("expected replace-all, got %q (%v)", occurrence, err)
+	}
+
+	base := setupRepo(t, "occurrence = \"middle\"\n", "dev/repo/.git/", nestedEnvPath)
+	path := filepath.Join(base, nestedEnvPath)
+	if _, err := envpath.Resolve(path, "dev", envpath.Options{}); err == nil || !strings.Contains(err.Error(), ".cenv.toml:1") {
+		t.Fatalf("expected an error naming the config line, got %v", err)
+	}
+}
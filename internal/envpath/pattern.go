@@ -0,0 +1,64 @@
+package envpath
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Pattern recognises environment directories by shape rather than by name.
+// Entries starting with "^" are regular expressions; entries containing "*",
+// "?" or "[" are globs. Both are matched case-insensitively against a single
+// path segment.
+type Pattern struct {
+	Raw    string
+	Origin Origin
+
+	re  *regexp.Regexp
+	err error
+}
+
+// isPattern reports whether a configuration entry should be treated as a
+// pattern instead of a literal environment name.
+func isPattern(entry string) bool {
+	return strings.HasPrefix(entry, "^") || strings.ContainsAny(entry, "*?[")
+}
+
+func newPattern(raw string, origin Origin) Pattern {
+	p := Pattern{Raw: raw, Origin: origin}
+	if strings.HasPrefix(raw, "^") {
+		p.re, p.err = regexp.Compile("(?i)" + raw)
+	} else if _, err := path.Match(strings.ToLower(raw), ""); err != nil {
+		p.err = fmt.Errorf("invalid glob: %w", err)
+	}
+	return p
+}
+
+// Err returns the compilation error of an invalid pattern.
+func (p *Pattern) Err() error {
+	return p.err
+}
+
+// Match reports whether segment matches the pattern. Invalid patterns never
+// match.
+func (p *Pattern) Match(segment string) bool {
+	if p.err != nil {
+		return false
+	}
+	if p.re != nil {
+		return p.re.MatchString(segment)
+	}
+	ok, _ := path.Match(strings.ToLower(p.Raw), strings.ToLower(segment))
+	return ok
+}
+
+// matchPattern returns the first pattern that matches segment.
+func (c *Config) matchPattern(segment string) (*Pattern, bool) {
+	for i := range c.Patterns {
+		if c.Patterns[i].Match(segment) {
+			return &c.Patterns[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,68 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestSwitchDetectsEnvironmentsByPattern(t *testing.T) {
+	home := envpath.IsolateConfig(t)
+	envpath.WriteFile(t, filepath.Join(home, ".cenvrc"), `prod-*
+^(dev|test)-[a-z]{2}-[a-z]+-\d$
+`)
+
+	root := t.TempDir()
+	cases := map[string]string{
+		filepath.Join(root, "prod-us-east-1", "app"):    filepath.Join(root, "staging", "app"),
+		filepath.Join(root, "test-eu-central-1", "app"): filepath.Join(root, "staging", "app"),
+	}
+	for from, expected := range cases {
+		mustMkdirAll(t, from)
+		target, err := envpath.Switch(from, "staging")
+		if err != nil {
+			t.Fatalf("Switch(%q) returned error: %v", from, err)
+		}
+		if target != expected {
+			t.Fatalf("Switch(%q): expected %q, got %q", from, expected, target)
+		}
+	}
+
+	unmatched := filepath.Join(root, "qa-eu-central-1", "app")
+	mustMkdirAll(t, unmatched)
+	if _, err := envpath.Switch(unmatched, "staging"); err == nil {
+		t.Fatalf("expected %q not to match any pattern", unmatched)
+	}
+}
+
+func TestSwitchRejectsPatternTarget(t *testing.T) {
+	envpath.IsolateConfig(t)
+
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "app")
+	mustMkdirAll(t, devPath)
+
+	if _, err := envpath.Switch(devPath, "prod-*"); err == nil {
+		t.Fatalf("expected error for pattern target")
+	}
+}
+
+func TestCheckReportsInvalidPatterns(t *testing.T) {
+	home := envpath.IsolateConfig(t)
+	envpath.WriteFile(t, filepath.Join(home, ".cenvrc"), "prod-[\n^(dev\n")
+
+	cfg, err := envpath.LoadConfig(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("LoadConfig returned error: %v", err)
+	}
+	issues := cfg.Check()
+	if len(issues) != 2 {
+		t.Fatalf("expected two invalid patterns, got %v", issues)
+	}
+	for i, line := range []int{1, 2} {
+		if issues[i].Origin.Line != line {
+			t.Fatalf("expected issue %d on line %d, got %v", i, line, issues[i])
+		}
+	}
+}
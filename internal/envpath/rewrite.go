@@ -0,0 +1,76 @@
+package envpath
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Rule rewrites the path below the environment directory after the
+// environment has been switched. Rules apply in order; every rule whose From
+// and To match the environment pair is applied to the result of the previous
+// one.
+type Rule struct {
+	// From and To restrict the rule to an environment pair. Empty or "*"
+	// matches any environment.
+	From string
+	To   string
+	// Match is a regular expression applied to the slash-separated subpath,
+	// and Replace its replacement, which may reference captures as $1 or
+	// ${name}.
+	Match   string
+	Replace string
+	Origin  Origin
+
+	re *regexp.Regexp
+}
+
+// Rewrite records a rule that changed the subpath.
+type Rewrite struct {
+	Rule   *Rule
+	Before string
+	After  string
+}
+
+func newRule(from, to, match, replace string, origin Origin) (Rule, error) {
+	re, err := regexp.Compile(match)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid match expression %q: %w", match, err)
+	}
+	return Rule{From: from, To: to, Match: match, Replace: replace, Origin: origin, re: re}, nil
+}
+
+// matchesEnv reports whether the from or to environment want of a rule
+// covers env.
+func (c *Config) matchesEnv(want, env string) bool {
+	return want == "" || want == "*" || c.sameSegment(want, env)
+}
+
+// applyRewrites runs the configured rules for the environment pair over
+// subpath and returns the rewritten subpath together with the rules that
+// changed it.
+func (c *Config) applyRewrites(envFrom, envTo, subpath string) (string, []Rewrite, error) {
+	var applied []Rewrite
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		if !c.matchesEnv(rule.From, envFrom) || !c.matchesEnv(rule.To, envTo) || !rule.re.MatchString(subpath) {
+			continue
+		}
+		rewritten := rule.re.ReplaceAllString(subpath, rule.Replace)
+		if rewritten != "" {
+			rewritten = path.Clean(strings.Trim(rewritten, "/"))
+		}
+		if rewritten == ".." || strings.HasPrefix(rewritten, "../") {
+			return "", nil, fmt.Errorf("rewrite rule at %s turns %q into %q, which leaves the environment directory", rule.Origin, subpath, rewritten)
+		}
+		if rewritten == "." {
+			rewritten = ""
+		}
+		if rewritten != subpath {
+			applied = append(applied, Rewrite{Rule: rule, Before: subpath, After: rewritten})
+			subpath = rewritten
+		}
+	}
+	return subpath, applied, nil
+}
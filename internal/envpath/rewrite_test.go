@@ -0,0 +1,90 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestResolveAppliesRewriteRulesInOrder(t *testing.T) {
+	repo := setupRepo(t, `[[rewrite]]
+from = "dev"
+to = "prod"
+match = '^services/([^/]+)-dev(/|$)'
+replace = 'services/${1}${2}'
+
+[[rewrite]]
+to = "prod"
+match = '^services/'
+replace = 'regions/services/'
+
+[[rewrite]]
+from = "test"
+match = '.*'
+replace = 'never'
+`)
+	devPath := filepath.Join(repo, "dev", "services", "app-dev", "config")
+	mustMkdirAll(t, devPath)
+
+	result, err := envpath.Resolve(devPath, "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	expected := filepath.Join(repo, "prod", "regions", "services", "app", "config")
+	if result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+	if len(result.Rewrites) != 2 {
+		t.Fatalf("expected two rules to fire, got %+v", result.Rewrites)
+	}
+	first := result.Rewrites[0]
+	if first.Before != "services/app-dev/config" || first.After != "services/app/config" || first.Rule.Origin.Line != 1 {
+		t.Fatalf("unexpected first rewrite %+v", first)
+	}
+	if result.Rewrites[1].After != "regions/services/app/config" {
+		t.Fatalf("unexpected second rewrite %+v", result.Rewrites[1])
+	}
+}
+
+func TestResolveSkipsRulesForOtherEnvironmentPairs(t *testing.T) {
+	repo := setupRepo(t, `[[rewrite]]
+from = "dev"
+to = "prod"
+match = '-dev$'
+replace = ''
+`)
+	testPath := filepath.Join(repo, "test", "app-dev")
+	mustMkdirAll(t, testPath)
+
+	result, err := envpath.Resolve(testPath, "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	if expected := filepath.Join(repo, "prod", "app-dev"); result.Target != expected || len(result.Rewrites) != 0 {
+		t.Fatalf("expected untouched %q, got %q with %+v", expected, result.Target, result.Rewrites)
+	}
+}
+
+func TestResolveRejectsRewriteLeavingEnvironment(t *testing.T) {
+	repo := setupRepo(t, `[[rewrite]]
+match = '^app'
+replace = '../../etc'
+`)
+	devPath := filepath.Join(repo, "dev", "app")
+	mustMkdirAll(t, devPath)
+
+	if _, err := envpath.Switch(devPath, "prod"); err == nil {
+		t.Fatalf("expected error for a rewrite that escapes the environment directory")
+	}
+}
+
+func TestLoadConfigRejectsInvalidRewrite(t *testing.T) {
+	repo := setupRepo(t, `[[rewrite]]
+match = '('
+replace = ''
+`)
+	if _, err := envpath.LoadConfig(repo, ""); err == nil {
+		t.Fatalf("expected error for invalid rewrite expression")
+	}
+}
@@ -0,0 +1,47 @@
+package envpath
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var defaultRootMarkers = []string{".git"}
+
+// rootMarkers returns the marker names that identify a repository root:
+// the explicit markers when given, otherwise the space-separated names from
+// $CENV_ROOT_MARKERS, then the configured root_markers, then ".git".
+func rootMarkers(explicit []string, cfg *Config) []string {
+	if len(explicit) > 0 {
+		return explicit
+	}
+	if fromEnv := strings.Fields(os.Getenv("CENV_ROOT_MARKERS")); len(fromEnv) > 0 {
+		return fromEnv
+	}
+	if len(cfg.RootMarkers.Value) > 0 {
+		return cfg.RootMarkers.Value
+	}
+	return defaultRootMarkers
+}
+
+// FindRoot walks up from path and returns the first directory that contains
+// one of the marker entries. Markers may be files or directories. The second
+// return value is false when no ancestor carries a marker.
+func FindRoot(path string, markers []string) (string, bool) {
+	dir := filepath.Clean(path)
+	for {
+		for _, marker := range markers {
+			if marker == "" {
+				continue
+			}
+			if _, err := os.Lstat(filepath.Join(dir, marker)); err == nil {
+				return dir, true
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
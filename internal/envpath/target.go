@@ -0,0 +1,62 @@
+package envpath
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CanonicalEnv resolves target to the name of a configured environment. It
+// tries, in order, an exact name, an alias, and a unique prefix of an
+// environment name, compared according to the configured case mode. Targets
+// that match nothing are returned unchanged; a prefix shared by several
+// environments yields an *AmbiguousTargetError. A target matching a component
+// of the compound pattern, such as "prod", is never expanded as a prefix, so
+// that it can replace that component of the current environment.
+func (c *Config) CanonicalEnv(target string) (string, error) {
+	name, _, err := c.canonicalEnv(target)
+	return name, err
+}
+
+// canonicalEnv is CanonicalEnv that also explains how target was resolved.
+func (c *Config) canonicalEnv(target string) (name, reason string, err error) {
+	equal, hasPrefix := strings.EqualFold, hasFoldPrefix
+	if !c.foldTarget(target) {
+		equal, hasPrefix = func(a, b string) bool { return a == b }, strings.HasPrefix
+	}
+
+	for _, env := range c.Envs {
+		if equal(env.Name, target) {
+			return env.Name, "environment name", nil
+		}
+	}
+	for _, env := range c.Envs {
+		for _, alias := range env.Aliases {
+			if equal(alias.Name, target) {
+				return env.Name, fmt.Sprintf("alias of %q", env.Name), nil
+			}
+		}
+	}
+
+	if component, ok := c.Compound.Component(target); ok {
+		return target, fmt.Sprintf("compound %s; replaces that component of the current environment", component), nil
+	}
+
+	var candidates []string
+	for _, env := range c.Envs {
+		if hasPrefix(env.Name, target) {
+			candidates = append(candidates, env.Name)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return target, "not configured; used as the directory name", nil
+	case 1:
+		return candidates[0], fmt.Sprintf("unique prefix of %q", candidates[0]), nil
+	default:
+		return "", "", &AmbiguousTargetError{Target: target, Candidates: candidates}
+	}
+}
+
+func hasFoldPrefix(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
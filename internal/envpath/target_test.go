@@ -0,0 +1,104 @@
+package envpath_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestSwitchResolvesAliasesToCanonicalName(t *testing.T) {
+	home := envpath.IsolateConfig(t)
+	envpath.WriteFile(t, filepath.Join(home, ".config", "changeenv", "config.toml"), `[env.prod]
+aliases = ["production", "prd"]
+`)
+
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "app")
+	mustMkdirAll(t, devPath)
+
+	for _, target := range []string{"production", "PRD", "p", "pr"} {
+		result, err := envpath.Resolve(devPath, target, envpath.Options{})
+		if err != nil {
+			t.Fatalf("Resolve(%q) returned error: %v", target, err)
+		}
+		expected := filepath.Join(root, "prod", "app")
+		if result.Target != expected {
+			t.Fatalf("Resolve(%q): expected %q, got %q", target, expected, result.Target)
+		}
+		if result.EnvFrom != "dev" || result.EnvTo != "prod" {
+			t.Fatalf("Resolve(%q): unexpected environments %q -> %q", target, result.EnvFrom, result.EnvTo)
+		}
+	}
+}
+
+func TestSwitchRejectsAmbiguousPrefix(t *testing.T) {
+	envpath.IsolateConfig(t)
+	t.Setenv("CENV_ENVIRONMENTS", "preview")
+
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "app")
+	mustMkdirAll(t, devPath)
+
+	_, err := envpath.Switch(devPath, "pr")
+	var ambiguous *envpath.AmbiguousTargetError
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected AmbiguousTargetError, got %v", err)
+	}
+	if got := strings.Join(ambiguous.Candidates, ","); got != "prod,preview" {
+		t.Fatalf("unexpected candidates %q", got)
+	}
+}
+
+func TestSwitchKeepsUnknownTargetVerbatim(t *testing.T) {
+	envpath.IsolateConfig(t)
+
+	root := t.TempDir()
+	devPath := filepath.Join(root, "dev", "app")
+	mustMkdirAll(t, devPath)
+
+	target, err := envpath.Switch(devPath, "sandbox")
+	if err != nil {
+		t.Fatalf("Switch returned error: %v", err)
+	}
+	expected := filepath.Join(root, "sandbox", "app")
+	if target != expected {
+		t.Fatalf("expected %q, got %q", expected, target)
+	}
+}
+
+func TestSwitchUsesConfiguredDirectoryNames(t *testing.T) {
+	home := envpath.IsolateConfig(t)
+	envpath.WriteFile(t, filepath.Join(home, ".config", "changeenv", "config.toml"), `[env.dev]
+dirs = ["development"]
+[env.prod]
+dirs = ["production", "prod"]
+`)
+
+	root := t.TempDir()
+	devPath := filepath.Join(root, "development", "app")
+	mustMkdirAll(t, devPath)
+
+	result, err := envpath.Resolve(devPath, "prod", envpath.Options{})
+	if err != nil {
+		t.Fatalf("Resolve returned error: %v", err)
+	}
+	expected := filepath.Join(root, "production", "app")
+	if result.Target != expected {
+		t.Fatalf("expected %q, got %q", expected, result.Target)
+	}
+	if result.EnvFrom != "dev" {
+		t.Fatalf("expected development to be recognised as dev, got %q", result.EnvFrom)
+	}
+
+	legacyProd := filepath.Join(root, "prod", "app")
+	target, err := envpath.Switch(legacyProd, "dev")
+	if err != nil {
+		t.Fatalf("Switch returned error: %v", err)
+	}
+	if expected := filepath.Join(root, "development", "app"); target != expected {
+		t.Fatalf("expected %q, got %q", expected, target)
+	}
+}
@@ -0,0 +1,94 @@
+package envpath
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// defaultTokenBoundaries are the characters that may surround an environment
+// token in a file name when token_boundaries is not configured.
+const defaultTokenBoundaries = "-_."
+
+// ReplaceEnvTokens replaces every occurrence of one of names in s with
+// replacement, compared according to the case mode. An occurrence only counts
+// as a token when it is not directly preceded or followed by a letter or
+// digit, so "dev" matches in "app-dev.yaml" and "dev_config" but not in
+// "device". The longest matching name wins.
+func (c *Config) ReplaceEnvTokens(s string, names []string, replacement string) string {
+	return c.replaceTokens(s, names, "", func(string) string { return replacement })
+}
+
+// tokenBoundaries returns the configured file name token boundaries.
+func (c *Config) tokenBoundaries() string {
+	if c.TokenBoundaries.Value != "" {
+		return c.TokenBoundaries.Value
+	}
+	return defaultTokenBoundaries
+}
+
+// replaceTokens replaces every token of s matching one of names, compared
+// according to the case mode, with replace applied to the matched text. A token must start and end
+// at the ends of s or next to one of the boundaries characters; when
+// boundaries is empty, any character other than a letter or digit will do.
+func (c *Config) replaceTokens(s string, names []string, boundaries string, replace func(match string) string) string {
+	isBoundary := func(r rune) bool {
+		if boundaries == "" {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}
+		return strings.ContainsRune(boundaries, r)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for i := 0; i < len(s); {
+		if i > 0 && !isBoundary(lastRune(s[:i])) {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+			continue
+		}
+		length := 0
+		for _, name := range names {
+			end := i + len(name)
+			if name == "" || len(name) <= length || end > len(s) || !c.sameSegment(s[i:end], name) {
+				continue
+			}
+			if end == len(s) || isBoundary(firstRune(s[end:])) {
+				length = len(name)
+			}
+		}
+		if length == 0 {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+			continue
+		}
+		sb.WriteString(s[last:i])
+		sb.WriteString(replace(s[i : i+length]))
+		i += length
+		last = i
+	}
+	if last == 0 {
+		return s
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// EnvTokens returns the names under which env may appear as a token: its
+// name and directory names, or env itself when it is not configured.
+func (c *Config) EnvTokens(env string) []string {
+	if e, ok := c.Lookup(env); ok {
+		return append([]string{e.Name}, e.DirNames()...)
+	}
+	return []string{env}
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
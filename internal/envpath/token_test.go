@@ -0,0 +1,33 @@
+package envpath_test
+
+import (
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestReplaceEnvTokens(t *testing.T) {
+	cfg := &envpath.Config{}
+	names := []string{"dev", "prod", "prod-eu"}
+	tests := []struct {
+		in, want string
+	}{
+		{"name: app-dev", "name: app-ENV"},
+		{"url: https://prod.example.com/dev", "url: https://ENV.example.com/ENV"},
+		{"DEV_MODE=1", "ENV_MODE=1"},
+		{"device: product", "device: product"},
+		{"region: prod-eu", "region: ENV"},
+		{"dev2", "dev2"},
+		{"dev", "ENV"},
+	}
+	for _, tt := range tests {
+		if got := cfg.ReplaceEnvTokens(tt.in, names, "ENV"); got != tt.want {
+			t.Fatalf("%q: expected %q, got %q", tt.in, tt.want, got)
+		}
+	}
+
+	cfg.Case.Value = envpath.CaseSensitive
+	if got := cfg.ReplaceEnvTokens("DEV_MODE=1 dev", names, "ENV"); got != "DEV_MODE=1 ENV" {
+		t.Fatalf("expected only the exact token to be replaced, got %q", got)
+	}
+}
@@ -0,0 +1,475 @@
+package envpath
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file implements the subset of TOML used by changeenv configuration
+// files: tables, arrays of tables, dotted keys, basic and literal strings,
+// integers, booleans, arrays and inline tables. Floats, dates and multi-line
+// strings are rejected.
+
+// tomlTable is a parsed TOML table that remembers key order and the line each
+// key was defined on.
+type tomlTable struct {
+	line    int
+	keys    []string
+	values  map[string]*tomlValue
+	defined bool
+}
+
+// tomlValue holds a string, int64, bool, []*tomlValue, *tomlTable or
+// []*tomlTable (for arrays of tables) together with its source line.
+type tomlValue struct {
+	line  int
+	value any
+}
+
+func newTomlTable(line int) *tomlTable {
+	return &tomlTable{line: line, values: make(map[string]*tomlValue)}
+}
+
+func (t *tomlTable) set(key string, value *tomlValue) {
+	if _, ok := t.values[key]; !ok {
+		t.keys = append(t.keys, key)
+	}
+	t.values[key] = value
+}
+
+type tomlError struct {
+	line int
+	msg  string
+}
+
+func (e *tomlError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+// parseTOML parses src into its root table.
+func parseTOML(src string) (*tomlTable, error) {
+	p := &tomlParser{src: src, line: 1}
+	root := newTomlTable(1)
+	root.defined = true
+	current := root
+
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+		var err error
+		if p.peek() == '[' {
+			current, err = p.parseHeader(root)
+		} else {
+			err = p.parseKeyValue(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return &tomlError{line: p.line, msg: fmt.Sprintf(format, args...)}
+}
+
+// skipSpace skips spaces and tabs on the current line.
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment skips a comment up to, but not including, the line break.
+func (p *tomlParser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, line breaks and comments.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			p.skipComment()
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace()
+	p.skipComment()
+	if p.eof() {
+		return nil
+	}
+	if p.peek() == '\r' {
+		p.pos++
+	}
+	if p.peek() != '\n' {
+		return p.errorf("expected end of line, found %q", p.peek())
+	}
+	p.pos++
+	p.line++
+	return nil
+}
+
+func (p *tomlParser) parseHeader(root *tomlTable) (*tomlTable, error) {
+	line := p.line
+	p.pos++
+	isArray := p.peek() == '['
+	if isArray {
+		p.pos++
+	}
+	p.skipSpace()
+	keys, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	closing := "]"
+	if isArray {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return nil, p.errorf("expected %q to close table header", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.descend(root, keys[:len(keys)-1], line)
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	existing := parent.values[last]
+
+	if isArray {
+		table := newTomlTable(line)
+		table.defined = true
+		if existing == nil {
+			parent.set(last, &tomlValue{line: line, value: []*tomlTable{table}})
+			return table, nil
+		}
+		tables, ok := existing.value.([]*tomlTable)
+		if !ok {
+			return nil, p.errorf("key %q is already defined as a non-array value", strings.Join(keys, "."))
+		}
+		existing.value = append(tables, table)
+		return table, nil
+	}
+
+	if existing == nil {
+		table := newTomlTable(line)
+		table.defined = true
+		parent.set(last, &tomlValue{line: line, value: table})
+		return table, nil
+	}
+	table, ok := existing.value.(*tomlTable)
+	if !ok || table.defined {
+		return nil, p.errorf("table %q is defined more than once", strings.Join(keys, "."))
+	}
+	table.defined = true
+	table.line = line
+	existing.line = line
+	return table, nil
+}
+
+// descend walks keys from table, creating implicit tables as needed. For
+// arrays of tables the most recently defined element is used.
+func (p *tomlParser) descend(table *tomlTable, keys []string, line int) (*tomlTable, error) {
+	for _, key := range keys {
+		existing := table.values[key]
+		if existing == nil {
+			child := newTomlTable(line)
+			table.set(key, &tomlValue{line: line, value: child})
+			table = child
+			continue
+		}
+		switch v := existing.value.(type) {
+		case *tomlTable:
+			table = v
+		case []*tomlTable:
+			table = v[len(v)-1]
+		default:
+			return nil, p.errorf("key %q is already defined as a value", key)
+		}
+	}
+	return table, nil
+}
+
+func (p *tomlParser) parseKeyValue(table *tomlTable) error {
+	line := p.line
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.peek() != '=' {
+		return p.errorf("expected \"=\" after key %q", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipSpace()
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	value.line = line
+
+	parent, err := p.descend(table, keys[:len(keys)-1], line)
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := parent.values[last]; ok {
+		return p.errorf("key %q is defined more than once", strings.Join(keys, "."))
+	}
+	parent.set(last, value)
+	return nil
+}
+
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var (
+			key string
+			err error
+		)
+		switch p.peek() {
+		case '"':
+			key, err = p.parseBasicString()
+		case '\'':
+			key, err = p.parseLiteralString()
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("expected key, found %q", p.peek())
+			}
+			key = p.src[start:p.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (*tomlValue, error) {
+	line := p.line
+	switch c := p.peek(); {
+	case c == '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		s, err := p.parseBasicString()
+		return &tomlValue{line: line, value: s}, err
+	case c == '\'':
+		if strings.HasPrefix(p.src[p.pos:], `'''`) {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		s, err := p.parseLiteralString()
+		return &tomlValue{line: line, value: s}, err
+	case c == '[':
+		return p.parseArray()
+	case c == '{':
+		return p.parseInlineTable()
+	case c == 't' || c == 'f':
+		for _, word := range []string{"true", "false"} {
+			if strings.HasPrefix(p.src[p.pos:], word) {
+				p.pos += len(word)
+				return &tomlValue{line: line, value: word == "true"}, nil
+			}
+		}
+	case c == '+' || c == '-' || c >= '0' && c <= '9':
+		start := p.pos
+		for !p.eof() && strings.IndexByte("+-_0123456789", p.peek()) >= 0 {
+			p.pos++
+		}
+		if !p.eof() && strings.IndexByte(".eE:", p.peek()) >= 0 {
+			return nil, p.errorf("floats and dates are not supported")
+		}
+		n, err := strconv.ParseInt(strings.ReplaceAll(p.src[start:p.pos], "_", ""), 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer %q", p.src[start:p.pos])
+		}
+		return &tomlValue{line: line, value: n}, nil
+	}
+	return nil, p.errorf("invalid value starting with %q", p.peek())
+}
+
+func (p *tomlParser) parseArray() (*tomlValue, error) {
+	value := &tomlValue{line: p.line}
+	var items []*tomlValue
+	p.pos++
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			value.value = items
+			return value, nil
+		}
+		item, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.skipBlank()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected \",\" or \"]\" in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (*tomlValue, error) {
+	table := newTomlTable(p.line)
+	table.defined = true
+	p.pos++
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		return &tomlValue{line: table.line, value: table}, nil
+	}
+	for {
+		if err := p.parseKeyValue(table); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+			p.skipSpace()
+		case '}':
+			p.pos++
+			return &tomlValue{line: table.line, value: table}, nil
+		default:
+			return nil, p.errorf("expected \",\" or \"}\" in inline table")
+		}
+	}
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	p.pos++
+	end := strings.IndexAny(p.src[p.pos:], "'\n")
+	if end == -1 || p.src[p.pos+end] != '\'' {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.src[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		switch c {
+		case '"':
+			p.pos++
+			return b.String(), nil
+		case '\\':
+			p.pos++
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			r, size := utf8.DecodeRuneInString(p.src[p.pos:])
+			b.WriteRune(r)
+			p.pos += size
+		}
+	}
+}
+
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+	if p.eof() {
+		return p.errorf("unterminated string")
+	}
+	c := p.peek()
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return p.errorf("invalid unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return p.errorf("invalid unicode escape %q", p.src[p.pos:p.pos+size])
+		}
+		b.WriteRune(rune(code))
+		p.pos += size
+	default:
+		return p.errorf("invalid escape sequence \\%c", c)
+	}
+	return nil
+}
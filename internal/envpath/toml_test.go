@@ -0,0 +1,80 @@
+package envpath
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTOMLTablesAndValues(t *testing.T) {
+	src := `# comment
+order = ["prod", 'dev'] # trailing comment
+root_markers = [
+  ".git",
+  ".cenv-root",
+]
+
+[env.prod]
+description = "Production \"live\""
+meta = { owner = "platform", tier = 1 }
+
+[env.dev.meta]
+owner = 'dev\team'
+
+[[rewrite]]
+enabled = true
+[[rewrite]]
+enabled = false
+`
+	root, err := parseTOML(src)
+	if err != nil {
+		t.Fatalf("parseTOML returned error: %v", err)
+	}
+
+	if got := strings.Join(root.keys, ","); got != "order,root_markers,env,rewrite" {
+		t.Fatalf("unexpected key order %q", got)
+	}
+	markers := root.values["root_markers"].value.([]*tomlValue)
+	if len(markers) != 2 || markers[1].value != ".cenv-root" || markers[1].line != 5 {
+		t.Fatalf("unexpected root_markers %+v", markers)
+	}
+
+	envs := root.values["env"].value.(*tomlTable)
+	prod := envs.values["prod"].value.(*tomlTable)
+	if got := prod.values["description"].value; got != `Production "live"` {
+		t.Fatalf("unexpected description %q", got)
+	}
+	if line := prod.values["description"].line; line != 9 {
+		t.Fatalf("expected description on line 9, got %d", line)
+	}
+	meta := prod.values["meta"].value.(*tomlTable)
+	if meta.values["tier"].value != int64(1) {
+		t.Fatalf("unexpected tier %v", meta.values["tier"].value)
+	}
+	devMeta := envs.values["dev"].value.(*tomlTable).values["meta"].value.(*tomlTable)
+	if got := devMeta.values["owner"].value; got != `dev\team` {
+		t.Fatalf("literal string should not process escapes, got %q", got)
+	}
+
+	rewrites := root.values["rewrite"].value.([]*tomlTable)
+	if len(rewrites) != 2 || rewrites[1].values["enabled"].value != false {
+		t.Fatalf("unexpected array of tables %+v", rewrites)
+	}
+}
+
+func TestParseTOMLReportsLine(t *testing.T) {
+	cases := map[string]string{
+		"duplicate key":   "a = 1\na = 2\n",
+		"duplicate table": "[env]\n[env]\n",
+		"missing value":   "a = 1\nb =\n",
+		"float":           "a = 1\nb = 1.5\n",
+	}
+	for name, src := range cases {
+		_, err := parseTOML(src)
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+		if !strings.HasPrefix(err.Error(), "line 2:") {
+			t.Fatalf("%s: expected error on line 2, got %v", name, err)
+		}
+	}
+}
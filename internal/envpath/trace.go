@@ -0,0 +1,101 @@
+package envpath
+
+import "fmt"
+
+// Trace records the steps taken while resolving a path, so that a surprising
+// result can be explained without reading the resolver.
+type Trace struct {
+	// Input is the path as passed in and Cleaned its cleaned form.
+	Input   string
+	Cleaned string
+	// Volume, Absolute and Parts are Cleaned split into its segments.
+	Volume   string
+	Absolute bool
+	Parts    []string
+	// Root is the repository root, or empty when none of RootMarkers was
+	// found and the whole path was searched.
+	Root        string
+	RootMarkers []string
+	// Target is the target environment as given. TargetEnv is its canonical
+	// name, TargetReason explains how it was resolved and TargetDir is the
+	// directory written into the path.
+	Target       string
+	TargetEnv    string
+	TargetReason string
+	TargetDir    string
+	// Layout is the matching layout template, or nil.
+	Layout *Layout
+	// Occurrence is the policy used to choose among Candidates.
+	Occurrence Occurrence
+	// Segments lists every segment of Parts and whether it was recognised as
+	// an environment directory.
+	Segments []SegmentCheck
+	// EnvIndex is the index in Parts of the chosen environment directory, or
+	// -1 if none was found. EnvLen is the number of segments it spans.
+	EnvIndex int
+	EnvLen   int
+	// Candidates lists every environment directory found in the path.
+	Candidates []Candidate
+	// Steps describes each change made to the path, in order.
+	Steps []string
+	// Rewrites lists the rewrite rules that fired.
+	Rewrites []Rewrite
+	// Checks lists every existence check, in the order they were made.
+	Checks []ExistenceCheck
+	// Assembled is the exact counterpart path before any --nearest fallback.
+	Assembled string
+	// Result is the outcome, or nil when resolution failed.
+	Result *Result
+}
+
+// SegmentCheck is the outcome of testing one path segment against the known
+// environments.
+type SegmentCheck struct {
+	Index   int
+	Segment string
+	Matched bool
+	// Env is the environment the segment belongs to when Matched.
+	Env string
+	// Reason explains why the segment matched or not.
+	Reason string
+}
+
+// ExistenceCheck records whether a candidate directory exists.
+type ExistenceCheck struct {
+	Path   string
+	Exists bool
+}
+
+// Explain resolves fromPath like Resolve and returns a trace of every step.
+// The trace is returned even when resolution fails, covering the steps taken
+// up to the error.
+func Explain(fromPath, targetEnv string, opts Options) (*Trace, error) {
+	trace := &Trace{Input: fromPath, Target: targetEnv, EnvIndex: -1}
+	result, err := resolve(fromPath, targetEnv, opts, nil, trace)
+	trace.Result = result
+	return trace, err
+}
+
+// The recording methods below are no-ops on a nil *Trace, so the resolver can
+// call them unconditionally.
+
+func (t *Trace) segment(check SegmentCheck) {
+	if t != nil {
+		t.Segments = append(t.Segments, check)
+	}
+}
+
+func (t *Trace) step(format string, args ...any) {
+	if t != nil {
+		t.Steps = append(t.Steps, fmt.Sprintf(format, args...))
+	}
+}
+
+// exists reports whether path is a directory and records the check.
+func (t *Trace) exists(path string) bool {
+	ok := isDir(path)
+	if t != nil {
+		t.Checks = append(t.Checks, ExistenceCheck{Path: path, Exists: ok})
+	}
+	return ok
+}
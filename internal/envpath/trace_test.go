@@ -0,0 +1,73 @@
+package envpath_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"envchanger/internal/envpath"
+)
+
+func TestExplainRecordsEachStep(t *testing.T) {
+	repo := setupRepo(t, "")
+	devPath := filepath.Join(repo, "dev", "app")
+	mustMkdirAll(t, devPath)
+	mustMkdirAll(t, filepath.Join(repo, "prod"))
+
+	trace, err := envpath.Explain(devPath+"/", "p", envpath.Options{Nearest: true})
+	if err != nil {
+		t.Fatalf("Explain returned error: %v", err)
+	}
+	if trace.Cleaned != devPath || trace.Root != repo {
+		t.Fatalf("unexpected cleaned path %q or root %q", trace.Cleaned, trace.Root)
+	}
+	if trace.TargetEnv != "prod" || !strings.Contains(trace.TargetReason, "prefix") {
+		t.Fatalf("unexpected target %q (%s)", trace.TargetEnv, trace.TargetReason)
+	}
+
+	envIndex := len(trace.Parts) - 2
+	if trace.EnvIndex != envIndex || trace.EnvLen != 1 {
+		t.Fatalf("expected env at index %d, got %d", envIndex, trace.EnvIndex)
+	}
+	if len(trace.Segments) != len(trace.Parts) {
+		t.Fatalf("expected every segment to be checked, got %+v", trace.Segments)
+	}
+	if check := trace.Segments[envIndex-1]; check.Matched || !strings.Contains(check.Reason, "above the repository root") {
+		t.Fatalf("unexpected check above the root %+v", check)
+	}
+	if check := trace.Segments[envIndex]; !check.Matched || check.Env != "dev" {
+		t.Fatalf("unexpected check for the env segment %+v", check)
+	}
+	if check := trace.Segments[envIndex+1]; check.Matched {
+		t.Fatalf("expected %q not to match", check.Segment)
+	}
+
+	exact := filepath.Join(repo, "prod", "app")
+	if trace.Assembled != exact {
+		t.Fatalf("expected assembled path %q, got %q", exact, trace.Assembled)
+	}
+	want := []envpath.ExistenceCheck{{Path: exact, Exists: false}, {Path: filepath.Join(repo, "prod"), Exists: true}}
+	if len(trace.Checks) != len(want) || trace.Checks[0] != want[0] || trace.Checks[1] != want[1] {
+		t.Fatalf("expected checks %+v, got %+v", want, trace.Checks)
+	}
+	if trace.Result == nil || trace.Result.Target != filepath.Join(repo, "prod") {
+		t.Fatalf("unexpected result %+v", trace.Result)
+	}
+}
+
+func TestExplainReturnsTraceOnError(t *testing.T) {
+	envpath.IsolateConfig(t)
+	dir := filepath.Join(t.TempDir(), "services")
+	mustMkdirAll(t, dir)
+
+	trace, err := envpath.Explain(dir, "prod", envpath.Options{})
+	if err == nil {
+		t.Fatalf("expected error for a path outside any environment")
+	}
+	if trace == nil || trace.Result != nil || trace.EnvIndex != -1 {
+		t.Fatalf("unexpected trace %+v", trace)
+	}
+	if len(trace.Segments) == 0 || trace.Segments[len(trace.Segments)-1].Segment != "services" {
+		t.Fatalf("expected the checked segments to be recorded, got %+v", trace.Segments)
+	}
+}
@@ -0,0 +1,281 @@
+// Package textdiff computes line differences with Myers' algorithm and
+// formats them as unified diffs.
+package textdiff
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Kind is the kind of an edit.
+type Kind int
+
+const (
+	// Equal keeps a line present in both inputs.
+	Equal Kind = iota
+	// Delete removes a line of the first input.
+	Delete
+	// Insert adds a line of the second input.
+	Insert
+)
+
+// Edit is one step of an edit script. A is the index of the line in the first
+// input for Equal and Delete, B the index in the second input for Equal and
+// Insert.
+type Edit struct {
+	Kind Kind
+	A, B int
+}
+
+// SplitLines splits text into lines, each keeping its line break. The last
+// line has no line break when text does not end with one.
+func SplitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// Lines returns a shortest edit script turning a into b. Lines are compared
+// with equal, or exactly when equal is nil.
+//
+// It uses the linear space variant of Myers' algorithm, which splits the
+// inputs at the middle of a shortest script and recurses on both halves, so
+// memory stays proportional to len(a)+len(b) however much the inputs differ.
+func Lines(a, b []string, equal func(x, y string) bool) []Edit {
+	if equal == nil {
+		equal = func(x, y string) bool { return x == y }
+	}
+	size := len(a) + len(b) + 3
+	d := differ{
+		a:      a,
+		b:      b,
+		equal:  equal,
+		fwd:    make([]int, size),
+		bwd:    make([]int, size),
+		offset: len(b) + 1,
+	}
+	d.compare(0, len(a), 0, len(b))
+	return d.edits
+}
+
+// differ holds the state of Lines. fwd and bwd hold, per diagonal x-y plus
+// offset, the furthest x reached by the forward and backward searches.
+type differ struct {
+	a, b     []string
+	equal    func(x, y string) bool
+	fwd, bwd []int
+	offset   int
+	edits    []Edit
+}
+
+// compare appends the edits turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	for aLo < aHi && bLo < bHi && d.equal(d.a[aLo], d.b[bLo]) {
+		d.edits = append(d.edits, Edit{Kind: Equal, A: aLo, B: bLo})
+		aLo++
+		bLo++
+	}
+	aEnd := aHi
+	for aLo < aHi && bLo < bHi && d.equal(d.a[aHi-1], d.b[bHi-1]) {
+		aHi--
+		bHi--
+	}
+
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			d.edits = append(d.edits, Edit{Kind: Insert, A: aLo, B: y})
+		}
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			d.edits = append(d.edits, Edit{Kind: Delete, A: x, B: bLo})
+		}
+	default:
+		x, y := d.split(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		d.compare(x, aHi, y, bHi)
+	}
+
+	for ; aHi < aEnd; aHi, bHi = aHi+1, bHi+1 {
+		d.edits = append(d.edits, Edit{Kind: Equal, A: aHi, B: bHi})
+	}
+}
+
+// split returns a point on a shortest path from (aLo, bLo) to (aHi, bHi) that
+// lies at the end of its middle snake. The ranges must be non-empty and
+// differ in their first and last lines, so both halves are shorter paths.
+func (d *differ) split(aLo, aHi, bLo, bHi int) (int, int) {
+	fwd, bwd, off := d.fwd, d.bwd, d.offset
+	// Diagonals outside [kMin, kMax] leave the ranges.
+	kMin, kMax := aLo-bHi, aHi-bLo
+	fMid, bMid := aLo-bLo, aHi-bHi
+	fMin, fMax, bMin, bMax := fMid, fMid, bMid, bMid
+	odd := (fMid-bMid)&1 != 0
+	fwd[off+fMid] = aLo
+	bwd[off+bMid] = aHi
+
+	for {
+		// Extend the forward search by one edit.
+		if fMin > kMin {
+			fMin--
+			fwd[off+fMin-1] = -1
+		} else {
+			fMin++
+		}
+		if fMax < kMax {
+			fMax++
+			fwd[off+fMax+1] = -1
+		} else {
+			fMax--
+		}
+		for k := fMax; k >= fMin; k -= 2 {
+			var x int
+			if lo, hi := fwd[off+k-1], fwd[off+k+1]; lo >= hi {
+				x = lo + 1
+			} else {
+				x = hi
+			}
+			y := x - k
+			for x < aHi && y < bHi && d.equal(d.a[x], d.b[y]) {
+				x++
+				y++
+			}
+			fwd[off+k] = x
+			if odd && bMin <= k && k <= bMax && bwd[off+k] <= x {
+				return x, y
+			}
+		}
+
+		// Extend the backward search by one edit.
+		if bMin > kMin {
+			bMin--
+			bwd[off+bMin-1] = math.MaxInt
+		} else {
+			bMin++
+		}
+		if bMax < kMax {
+			bMax++
+			bwd[off+bMax+1] = math.MaxInt
+		} else {
+			bMax--
+		}
+		for k := bMax; k >= bMin; k -= 2 {
+			var x int
+			if lo, hi := bwd[off+k-1], bwd[off+k+1]; lo < hi {
+				x = lo
+			} else {
+				x = hi - 1
+			}
+			y := x - k
+			for x > aLo && y > bLo && d.equal(d.a[x-1], d.b[y-1]) {
+				x--
+				y--
+			}
+			bwd[off+k] = x
+			if !odd && fMin <= k && k <= fMax && x <= fwd[off+k] {
+				return x, y
+			}
+		}
+	}
+}
+
+// Stat counts the inserted and deleted lines of an edit script.
+func Stat(edits []Edit) (insertions, deletions int) {
+	for _, edit := range edits {
+		switch edit.Kind {
+		case Insert:
+			insertions++
+		case Delete:
+			deletions++
+		}
+	}
+	return insertions, deletions
+}
+
+// Changed reports whether an edit script changes anything.
+func Changed(edits []Edit) bool {
+	insertions, deletions := Stat(edits)
+	return insertions+deletions > 0
+}
+
+// Unified formats the edit script turning a into b as a unified diff with
+// context lines around each change, headed by fromName and toName. It
+// returns "" when nothing changed.
+func Unified(fromName, toName string, a, b []string, edits []Edit, context int) string {
+	if !Changed(edits) {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Positions in a and b before each edit, for hunk headers.
+	aPos := make([]int, len(edits)+1)
+	bPos := make([]int, len(edits)+1)
+	for i, edit := range edits {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if edit.Kind != Insert {
+			aPos[i+1]++
+		}
+		if edit.Kind != Delete {
+			bPos[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].Kind == Equal {
+			i++
+			continue
+		}
+		start := max(i-context, 0)
+		end := i
+		// Extend the hunk while the next change is within reach of the
+		// trailing context.
+		for j := i; j < len(edits); j++ {
+			if edits[j].Kind != Equal {
+				end = j + 1
+			} else if j-end >= 2*context {
+				break
+			}
+		}
+		end = min(end+context, len(edits))
+
+		aLen, bLen := aPos[end]-aPos[start], bPos[end]-bPos[start]
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aPos[start], aLen), hunkRange(bPos[start], bLen))
+		for _, edit := range edits[start:end] {
+			switch edit.Kind {
+			case Equal:
+				writeLine(&sb, ' ', a[edit.A])
+			case Delete:
+				writeLine(&sb, '-', a[edit.A])
+			case Insert:
+				writeLine(&sb, '+', b[edit.B])
+			}
+		}
+		i = end
+	}
+	return sb.String()
+}
+
+func hunkRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+func writeLine(sb *strings.Builder, prefix byte, line string) {
+	sb.WriteByte(prefix)
+	sb.WriteString(line)
+	if !strings.HasSuffix(line, "\n") {
+		sb.WriteString("\n\\ No newline at end of file\n")
+	}
+}
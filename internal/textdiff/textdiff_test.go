@@ -0,0 +1,111 @@
+package textdiff
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestLinesProducesShortestScript(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		ins, dels int
+	}{
+		{"", "", 0, 0},
+		{"a\nb\nc\n", "a\nb\nc\n", 0, 0},
+		{"", "a\nb\n", 2, 0},
+		{"a\nb\n", "", 0, 2},
+		{"a\nb\nc\na\nb\nb\na\n", "c\nb\na\nb\na\nc\n", 2, 3},
+	}
+	for _, tt := range tests {
+		a, b := SplitLines(tt.a), SplitLines(tt.b)
+		edits := Lines(a, b, nil)
+		ins, dels := Stat(edits)
+		if ins != tt.ins || dels != tt.dels {
+			t.Fatalf("%q -> %q: expected +%d -%d, got +%d -%d", tt.a, tt.b, tt.ins, tt.dels, ins, dels)
+		}
+		// Replaying the script must turn a into b.
+		var got []string
+		for _, edit := range edits {
+			switch edit.Kind {
+			case Equal:
+				got = append(got, a[edit.A])
+			case Insert:
+				got = append(got, b[edit.B])
+			}
+		}
+		if strings.Join(got, "") != tt.b {
+			t.Fatalf("%q -> %q: script produced %q", tt.a, tt.b, strings.Join(got, ""))
+		}
+	}
+}
+
+func TestLinesUsesEqual(t *testing.T) {
+	a, b := SplitLines("Name: A\nx\n"), SplitLines("name: a\ny\n")
+	edits := Lines(a, b, strings.EqualFold)
+	if ins, dels := Stat(edits); ins != 1 || dels != 1 {
+		t.Fatalf("expected only the second line to change, got +%d -%d", ins, dels)
+	}
+}
+
+func TestLinesKeepsMemoryLinearForDisjointInputs(t *testing.T) {
+	const n = 5000
+	a, b := make([]string, n), make([]string, n)
+	for i := range n {
+		a[i], b[i] = fmt.Sprintf("a%d\n", i), fmt.Sprintf("b%d\n", i)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	edits := Lines(a, b, nil)
+	runtime.ReadMemStats(&after)
+
+	if ins, dels := Stat(edits); ins != n || dels != n {
+		t.Fatalf("expected +%d -%d, got +%d -%d", n, n, ins, dels)
+	}
+	// Keeping every step of the search would take hundreds of megabytes.
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 8<<20 {
+		t.Fatalf("expected memory linear in the input size, allocated %d bytes", alloc)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := SplitLines("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	b := SplitLines("1\ntwo\n3\n4\n5\n6\n7\n8\n9\n10")
+	got := Unified("a/f", "b/f", a, b, Lines(a, b, nil), 1)
+	want := `--- a/f
++++ b/f
+@@ -1,3 +1,3 @@
+ 1
+-2
++two
+ 3
+@@ -9,2 +9,2 @@
+ 9
+-10
++10
+\ No newline at end of file
+`
+	if got != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, got)
+	}
+
+	merged := Unified("a/f", "b/f", a, b, Lines(a, b, nil), 4)
+	if strings.Count(merged, "@@ -") != 1 || !strings.Contains(merged, "@@ -1,10 +1,10 @@") {
+		t.Fatalf("expected close changes to share a hunk, got\n%s", merged)
+	}
+
+	if got := Unified("a/f", "b/f", a, a, Lines(a, a, nil), 3); got != "" {
+		t.Fatalf("expected no output for equal inputs, got %q", got)
+	}
+}
+
+func TestUnifiedAddedFile(t *testing.T) {
+	b := SplitLines("x\ny\n")
+	got := Unified("/dev/null", "b/f", nil, b, Lines(nil, b, nil), 3)
+	want := "--- /dev/null\n+++ b/f\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+	if got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}